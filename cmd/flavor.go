@@ -1,26 +1,48 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
 	"github.com/spf13/cobra"
 )
 
 var (
-	flavorListHeader = []string{"ID", "Name", "CPU", "RAM", "Category"}
+	flavorListHeader = []string{"ID", "Name", "vCPUs", "RAM (GB)", "GPU", "Category"}
 	vcpus            int
 	ram              int
+	flavorMinVCPUs   int
+	flavorMinRAM     string
+	flavorGPU        bool
+	flavorSortBy     string
+	suggestVCPUs     int
+	suggestRAM       string
+	suggestGPU       bool
 )
 
+// flavorInfo is a flavor with parsed resources
+type flavorInfo struct {
+	ID        string
+	Name      string
+	ShortName string
+	VCPUs     int
+	// RAM in MB
+	RAM      int
+	Category string
+	GPU      bool
+}
+
 // flavorCmd represents the flavor command
 var flavorCmd = &cobra.Command{
 	Use:   "flavor",
 	Short: "Bizfly Cloud Flavor Interaction",
-	Long:  `Bizfly Cloud Flavor Action: List Flavors`,
+	Long:  `Bizfly Cloud Flavor Action: List Flavors, Suggest a Flavor`,
 	Run: func(cmd *cobra.Command, args []string) {
 	},
 }
@@ -31,44 +53,212 @@ var flavorListCmd = &cobra.Command{
 	Short: "List all flavor of Bizfly Cloud",
 	Long: `
 List all flavor of Bizfly Cloud.
+RAM is in GB by default, or with a unit: 8G, 8192M.
+The API does not return the GPUs of a flavor, GPU flavors are the flavors with "gpu" in their category or name.
 Use: bizfly flavor list
+Use: bizfly flavor list --min-vcpus 4 --min-ram 8G --category premium --gpu=false --sort-by ram
 `,
 	Run: func(cmd *cobra.Command, args []string) {
+		minRAM, err := parseRAM(flavorMinRAM)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		var gpu *bool
+		if cmd.Flags().Changed("gpu") {
+			gpu = &flavorGPU
+		}
+		if flavorSortBy != "" && flavorSortBy != "name" && flavorSortBy != "vcpus" && flavorSortBy != "ram" {
+			fmt.Printf("Invalid sort key %s. Use name, vcpus or ram\n", flavorSortBy)
+			os.Exit(1)
+		}
 		client, ctx := getApiClient(cmd)
-		flavors, err := client.CloudServer.Flavors().List(ctx)
+		flavors, err := listFlavors(ctx, client)
 		if err != nil {
 			fmt.Printf("List flavors error %v", err)
 			os.Exit(1)
 		}
-		var data [][]string
-		var flavorName string
+		var result []flavorInfo
 		for _, flavor := range flavors {
-			flavor.RAM = flavor.RAM / 1024
 			if category != "" && category != flavor.Category {
 				continue
 			}
 			if vcpus != -1 && vcpus != flavor.VCPUs {
 				continue
 			}
-			if ram != -1 && ram != flavor.RAM {
+			if ram != -1 && ram != flavor.RAM/1024 {
+				continue
+			}
+			if flavor.VCPUs < flavorMinVCPUs || flavor.RAM < minRAM {
 				continue
 			}
-			re := regexp.MustCompile(`(\d+c_\d+g)`)
-			result := re.FindStringSubmatch(flavor.Name)
-			if len(result) == 0 {
+			if gpu != nil && *gpu != flavor.GPU {
 				continue
 			}
-			if result[0] != "" {
-				flavorName = result[0]
+			if flavor.ShortName == "" {
+				continue
 			}
-			s := []string{flavor.ID, flavorName, strconv.Itoa(flavor.VCPUs), strconv.Itoa(flavor.RAM), flavor.Category}
-			data = append(data, s)
+			result = append(result, flavor)
+		}
+		sortFlavors(result, flavorSortBy)
+		var data [][]string
+		for _, flavor := range result {
+			data = append(data, flavorRow(flavor))
 		}
 		formatter.Output(flavorListHeader, data)
 
 	},
 }
 
+// flavorSuggestCmd represents the flavor suggest command
+var flavorSuggestCmd = &cobra.Command{
+	Use:   "suggest",
+	Short: "Suggest the smallest flavor with the given resources",
+	Long: `
+Suggest the smallest flavor which has at least the given vCPUs and RAM.
+The same flavor is used by 'bizfly server create --flavor auto --vcpus <vcpus> --ram <ram>'.
+Use: bizfly flavor suggest --vcpus 3 --ram 6G
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		minRAM, err := parseRAM(suggestRAM)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		var gpu *bool
+		if cmd.Flags().Changed("gpu") {
+			gpu = &suggestGPU
+		}
+		client, ctx := getApiClient(cmd)
+		flavor, err := suggestFlavor(ctx, client, suggestVCPUs, minRAM, category, gpu)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		formatter.Output(flavorListHeader, [][]string{flavorRow(*flavor)})
+	},
+}
+
+func listFlavors(ctx context.Context, client *gobizfly.Client) ([]flavorInfo, error) {
+	flavors, err := client.CloudServer.Flavors().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]flavorInfo, 0, len(flavors))
+	for _, flavor := range flavors {
+		result = append(result, flavorInfo{
+			ID:        flavor.ID,
+			Name:      flavor.Name,
+			ShortName: shortFlavorName(flavor.Name),
+			VCPUs:     flavor.VCPUs,
+			RAM:       flavor.RAM,
+			Category:  flavor.Category,
+			GPU:       isGPUFlavor(flavor.Name, flavor.Category),
+		})
+	}
+	useFullNamesForAmbiguousShortNames(result)
+	return result, nil
+}
+
+// useFullNamesForAmbiguousShortNames replaces the short name of the flavors with their full name
+// when several flavors of a category have the same short name, so the printed name can be used to create a server.
+func useFullNamesForAmbiguousShortNames(flavors []flavorInfo) {
+	count := make(map[string]int)
+	for _, flavor := range flavors {
+		if flavor.ShortName != "" {
+			count[flavor.Category+"/"+flavor.ShortName]++
+		}
+	}
+	for i, flavor := range flavors {
+		if count[flavor.Category+"/"+flavor.ShortName] > 1 {
+			flavors[i].ShortName = flavor.Name
+		}
+	}
+}
+
+// isGPUFlavor returns true for GPU flavors. The API does not return the GPUs of a flavor,
+// the GPU flavors are told apart by "gpu" in their category or their name.
+func isGPUFlavor(name, category string) bool {
+	return strings.Contains(strings.ToLower(category), "gpu") || strings.Contains(strings.ToLower(name), "gpu")
+}
+
+// flavorRow returns the row of a flavor printed by flavor list and flavor suggest
+func flavorRow(flavor flavorInfo) []string {
+	return []string{flavor.ID, flavor.ShortName, strconv.Itoa(flavor.VCPUs), strconv.Itoa(flavor.RAM / 1024),
+		strconv.FormatBool(flavor.GPU), flavor.Category}
+}
+
+// suggestFlavor returns the smallest flavor listed by 'bizfly flavor list' with at least the vCPUs and RAM in MB.
+// Flavors are compared by vCPUs, then by RAM.
+func suggestFlavor(ctx context.Context, client *gobizfly.Client, minVCPUs, minRAM int, category string,
+	gpu *bool) (*flavorInfo, error) {
+	flavors, err := listFlavors(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	var best *flavorInfo
+	for i, flavor := range flavors {
+		if flavor.ShortName == "" || (category != "" && category != flavor.Category) {
+			continue
+		}
+		if flavor.VCPUs < minVCPUs || flavor.RAM < minRAM {
+			continue
+		}
+		if (gpu == nil && flavor.GPU) || (gpu != nil && *gpu != flavor.GPU) {
+			continue
+		}
+		if best == nil || flavor.VCPUs < best.VCPUs || (flavor.VCPUs == best.VCPUs && flavor.RAM < best.RAM) {
+			best = &flavors[i]
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no flavor has at least %d vCPUs and %d GB RAM in category %s", minVCPUs,
+			minRAM/1024, category)
+	}
+	return best, nil
+}
+
+func sortFlavors(flavors []flavorInfo, sortBy string) {
+	switch sortBy {
+	case "name":
+		sort.SliceStable(flavors, func(i, j int) bool { return flavors[i].Name < flavors[j].Name })
+	case "vcpus":
+		sort.SliceStable(flavors, func(i, j int) bool { return flavors[i].VCPUs < flavors[j].VCPUs })
+	case "ram":
+		sort.SliceStable(flavors, func(i, j int) bool { return flavors[i].RAM < flavors[j].RAM })
+	}
+}
+
+// parseRAM parses a RAM size such as 8, 8G or 8192M and returns it in MB. A size without unit is in GB.
+func parseRAM(size string) (int, error) {
+	if size == "" {
+		return 0, nil
+	}
+	value, unit := strings.TrimSuffix(strings.ToUpper(size), "B"), 1024
+	if strings.HasSuffix(value, "G") {
+		value = strings.TrimSuffix(value, "G")
+	} else if strings.HasSuffix(value, "M") {
+		value, unit = strings.TrimSuffix(value, "M"), 1
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid RAM size %s. Use a size such as 8G or 8192M", size)
+	}
+	return n * unit, nil
+}
+
+// parseOptionalBool parses a flag which may be unset, true or false
+func parseOptionalBool(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
 func init() {
 	rootCmd.AddCommand(flavorCmd)
 	flavorCmd.AddCommand(flavorListCmd)
@@ -76,4 +266,15 @@ func init() {
 	flpf.StringVar(&category, "category", "", "Filter flavor by category")
 	flpf.IntVar(&vcpus, "cpu", -1, "Filter flavor by cpus")
 	flpf.IntVar(&ram, "ram", -1, "Filter flavor by ram")
+	flpf.IntVar(&flavorMinVCPUs, "min-vcpus", 0, "Filter flavor by minimum vCPUs")
+	flpf.StringVar(&flavorMinRAM, "min-ram", "", "Filter flavor by minimum RAM, e.g. 8G")
+	flpf.BoolVar(&flavorGPU, "gpu", false, "Filter GPU flavors (true) or flavors without GPU (false)")
+	flpf.StringVar(&flavorSortBy, "sort-by", "", "Sort flavors by name, vcpus or ram")
+
+	flavorCmd.AddCommand(flavorSuggestCmd)
+	fspf := flavorSuggestCmd.PersistentFlags()
+	fspf.StringVar(&category, "category", "", "Category of the flavor")
+	fspf.IntVar(&suggestVCPUs, "vcpus", 1, "Minimum vCPUs")
+	fspf.StringVar(&suggestRAM, "ram", "1G", "Minimum RAM, e.g. 6G")
+	fspf.BoolVar(&suggestGPU, "gpu", false, "Suggest a GPU flavor (true) or a flavor without GPU (false). Default is without GPU")
 }
@@ -0,0 +1,88 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import "testing"
+
+func TestShortFlavorName(t *testing.T) {
+	tests := map[string]string{
+		"nix.2c_4g":        "2c_4g",
+		"2c_4g_basic":      "2c_4g",
+		"16c_32g_gpu_a100": "16c_32g",
+		"2c_4g":            "2c_4g",
+		"custom":           "",
+	}
+	for name, want := range tests {
+		if got := shortFlavorName(name); got != want {
+			t.Errorf("shortFlavorName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestIsGPUFlavor(t *testing.T) {
+	tests := []struct {
+		name, category string
+		want           bool
+	}{
+		{name: "nix.2c_4g", category: "premium", want: false},
+		{name: "16c_32g_gpu_a100", category: "premium", want: true},
+		{name: "8c_64g", category: "GPU", want: true},
+	}
+	for _, tt := range tests {
+		if got := isGPUFlavor(tt.name, tt.category); got != tt.want {
+			t.Errorf("isGPUFlavor(%q, %q) = %v, want %v", tt.name, tt.category, got, tt.want)
+		}
+	}
+}
+
+func TestUseFullNamesForAmbiguousShortNames(t *testing.T) {
+	flavors := []flavorInfo{
+		{Name: "nix.2c_4g", ShortName: "2c_4g", Category: "premium"},
+		{Name: "2c_4g_legacy", ShortName: "2c_4g", Category: "premium"},
+		{Name: "2c_4g_basic", ShortName: "2c_4g", Category: "basic"},
+		{Name: "custom", ShortName: "", Category: "premium"},
+		{Name: "other", ShortName: "", Category: "premium"},
+	}
+	useFullNamesForAmbiguousShortNames(flavors)
+	want := []string{"nix.2c_4g", "2c_4g_legacy", "2c_4g", "", ""}
+	for i, flavor := range flavors {
+		if flavor.ShortName != want[i] {
+			t.Errorf("short name of %s = %q, want %q", flavor.Name, flavor.ShortName, want[i])
+		}
+	}
+}
+
+func TestParseRAM(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int
+		wantErr bool
+	}{
+		{size: "", want: 0},
+		{size: "8", want: 8192},
+		{size: "8G", want: 8192},
+		{size: "8gb", want: 8192},
+		{size: "512M", want: 512},
+		{size: "8T", wantErr: true},
+		{size: "-1G", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRAM(tt.size)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("parseRAM(%q) = %d, %v, want %d", tt.size, got, err, tt.want)
+		}
+	}
+}
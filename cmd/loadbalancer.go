@@ -16,15 +16,26 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
+	"net"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bizflycloud/bizflyctl/formatter"
 	"github.com/bizflycloud/gobizfly"
 	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	lbStatusActive = "ACTIVE"
+	lbStatusError  = "ERROR"
+	lbPollInterval = 5 * time.Second
 )
 
 var (
@@ -32,7 +43,7 @@ var (
 	poolListHeader          = []string{"ID", "Name", "Algorithm", "Protocol", "Operating Status"}
 	listenerListHeader      = []string{"ID", "Name", "Protocol", "Protocol Port", "Operating Status", "Default Pool ID"}
 	healthMonitorListHeader = []string{"ID", "Name", "Type", "Delay", "Max Retries", "Timeout", "Operating Status",
-		"Domain Name", "URL Path"}
+		"Domain Name", "URL Path", "HTTP Version", "Expected Codes"}
 	lbName                          string
 	lbType                          string
 	networkType                     string
@@ -50,6 +61,8 @@ var (
 	healthMonitorURLPath            string
 	healthMonitorMaxRetriesDown     int
 	healthMonitorMethod             string
+	healthMonitorDomainName         string
+	healthMonitorHTTPVersion        float32
 	listenerPoolName                string
 	defaultPoolID                   string
 	poolName                        string
@@ -57,6 +70,10 @@ var (
 	protocol                        string
 	sessionPersistenceType          string
 	sessionPersistenceCookieName    string
+	lbListeners                     []string
+	lbMembers                       []string
+	lbConfigFile                    string
+	lbWaitTimeout                   time.Duration
 )
 
 // serverCmd represents the server command
@@ -96,43 +113,75 @@ var lbHealthMonitorCmd = &cobra.Command{
 var lbCreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a load balancer",
-	Long: `Create a load balancer
-Example: bizflyctl loadbalancer create --name lb1 --type large --network-type external --listener 8080:8080 --listener 8443:8443 --pool-id pool1 --pool-id pool2 --health-monitor-id hm1`,
+	Long: `Create a load balancer with one or more listeners. Each listener has its own default pool and health monitor.
+The listener is given as protocol:port[:pool-name][:tls-ref] and can be repeated.
+Example: bizfly loadbalancer create --name lb1 --type large --network-type external --listener HTTP:80:web --listener TERMINATED_HTTPS:443:web-tls:<tls-ref> --member web=10.20.1.5:8080
+The members are given as pool-name=address:port and are added once the load balancer is active.
+Example: bizfly loadbalancer create --from-file lb.yaml (Sample config file in example)`,
 	Run: func(cmd *cobra.Command, args []string) {
-		client, ctx := getApiClient(cmd)
-		payload := gobizfly.LoadBalancerCreateRequest{
-			Name:         lbName,
-			Type:         lbType,
-			VPCNetworkID: vpcNetworkId,
-			NetworkType:  networkType,
-		}
-		if description != "" {
-			payload.Description = description
-		}
-		payload.Listeners = []gobizfly.LoadBalancerListener{}
-		payload.Listeners = append(payload.Listeners, gobizfly.LoadBalancerListener{
-			Name:          listenerName,
-			Protocol:      listenerProtocol,
-			DefaultTLSRef: tlsRef,
-			ProtocolPort:  listenerProtocolPort,
-			DefaultPool: gobizfly.ListenerPool{
-				LbAlgorithm: lbAlgorithm,
-				Name:        listenerPoolName,
-				Protocol:    listenerProtocol,
-				Members:     []string{},
-				HealthMonitor: gobizfly.ListenerHealthMonitor{
-					Delay:          healthMonitorDelay,
-					MaxRetries:     healthMonitorMaxRetries,
-					Timeout:        healthMonitorTimeout,
-					ExpectedCodes:  healthMonitorExpectedStatusCode,
-					URLPath:        healthMonitorURLPath,
-					MaxRetriesDown: healthMonitorMaxRetriesDown,
-					Type:           healthMonitorProtocol,
-					HTTPMethod:     healthMonitorMethod,
-				},
-			},
-		})
+		if lbConfigFile != "" && (len(lbListeners) > 0 || len(lbMembers) > 0) {
+			fmt.Println("--listener and --member can not be used with --from-file")
+			os.Exit(1)
+		}
+		hmType := ""
+		if cmd.Flags().Changed("health-monitor-protocol") {
+			hmType = healthMonitorProtocol
+		}
+		var payload gobizfly.LoadBalancerCreateRequest
+		var members []lbMemberSpec
+		if lbConfigFile != "" {
+			payload, members = loadLoadBalancerConfig(lbConfigFile)
+		} else {
+			payload = gobizfly.LoadBalancerCreateRequest{
+				Name:         lbName,
+				Type:         lbType,
+				VPCNetworkID: vpcNetworkId,
+				NetworkType:  networkType,
+				Description:  description,
+			}
+			if len(lbListeners) == 0 {
+				protocol := strings.ToUpper(listenerProtocol)
+				payload.Listeners = append(payload.Listeners, newLoadBalancerListener(listenerName, protocol,
+					listenerProtocolPort, tlsRef, listenerPoolName, stringOrDefault(hmType, defaultHealthMonitorType(protocol))))
+			}
+			for _, spec := range lbListeners {
+				listener, err := parseLoadBalancerListener(spec, hmType)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				payload.Listeners = append(payload.Listeners, listener)
+			}
+			for _, spec := range lbMembers {
+				member, err := parseLoadBalancerMember(spec)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				members = append(members, member)
+			}
+		}
+		if err := checkLoadBalancerMembers(payload.Listeners, members); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if payload.Name == "" {
+			fmt.Println("You need to specify the load balancer name with --name or in the config file")
+			os.Exit(1)
+		}
+		validateLoadBalancerListeners(payload.Listeners)
+		if estimateCost {
+			p := loadPricing()
+			line, err := p.loadBalancerLine(payload.Type)
+			if err != nil {
+				fmt.Printf("Estimate cost error: %v\n", err)
+				os.Exit(1)
+			}
+			p.printEstimate([]costLine{line})
+			return
+		}
 
+		client, ctx := getApiClient(cmd)
 		lb, err := client.CloudLoadBalancer.Create(ctx, &payload)
 		if err != nil {
 			log.Fatalf("Error creating load balancer: %v", err)
@@ -140,6 +189,13 @@ Example: bizflyctl loadbalancer create --name lb1 --type large --network-type ex
 		var data [][]string
 		data = append(data, []string{lb.ID, lb.Name, lb.NetworkType, lb.VipAddress, lb.OperatingStatus, lb.Type})
 		formatter.Output(lbListHeader, data)
+		if len(members) == 0 {
+			return
+		}
+		if err := addLoadBalancerMembers(ctx, client, lb.ID, members); err != nil {
+			fmt.Printf("Add members error: %v. Add them with bizfly loadbalancer member add\n", err)
+			os.Exit(1)
+		}
 	},
 }
 
@@ -468,11 +524,7 @@ Example: bizfly loadbalancer listener get fd554aac-9ab1-11ea-b09d-bbaf82f02f58
 			}
 			log.Fatal(err)
 		}
-		var data [][]string
-		data = append(data, []string{healthMontior.ID, healthMontior.Name, healthMontior.Type,
-			strconv.Itoa(healthMontior.Delay), strconv.Itoa(healthMontior.TimeOut), strconv.Itoa(healthMontior.MaxRetries),
-			healthMontior.DomainName, healthMontior.UrlPath})
-		formatter.Output(healthMonitorListHeader, data)
+		formatter.Output(healthMonitorListHeader, [][]string{healthMonitorRow(healthMontior)})
 	},
 }
 
@@ -503,66 +555,142 @@ var lbHealthMonitorCreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create health monitor of a listener",
 	Long: `Create health monitor of a listener with listener ID as input
-Example: bizfly loadbalancer listener create <pool-id> --name sadjf --type HTTP --delay 10 --timeout 10 --max-retries 3 --domain-name www.google.com --url-path /
+Example: bizfly loadbalancer health-monitor create <pool-id> --name sadjf --type HTTP --delay 10 --timeout 10 --max-retries 3 --domain-name www.google.com --url-path / --expected-codes 200-204
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) > 1 {
 			fmt.Printf("Unknow variable %s", strings.Join(args[1:], ""))
 		}
-		client, ctx := getApiClient(cmd)
+		hmType := strings.ToUpper(healthMonitorProtocol)
+		if err := validateHealthMonitorFlags(cmd, hmType); err != nil {
+			log.Fatal(err)
+		}
+		settings := healthMonitorSettings{
+			Type:          hmType,
+			URLPath:       healthMonitorURLPath,
+			ExpectedCodes: healthMonitorExpectedStatusCode,
+			DomainName:    healthMonitorDomainName,
+		}
+		if cmd.Flags().Changed("http-version") {
+			settings.HTTPVersion = healthMonitorHTTPVersion
+		} else if healthMonitorDomainName != "" {
+			settings.HTTPVersion = 1.1
+		}
+		if err := validateHealthMonitor(settings); err != nil {
+			log.Fatal(err)
+		}
 		payload := gobizfly.HealthMonitorCreateRequest{
 			Name:           healthMonitorName,
-			Type:           healthMonitorProtocol,
+			Type:           hmType,
 			Delay:          healthMonitorDelay,
 			TimeOut:        healthMonitorTimeout,
 			MaxRetries:     healthMonitorMaxRetries,
-			URLPath:        healthMonitorURLPath,
 			PoolID:         args[0],
 			MaxRetriesDown: healthMonitorMaxRetriesDown,
-			HTTPMethod:     healthMonitorMethod,
-			ExpectedCodes:  healthMonitorExpectedStatusCode,
 		}
+		if isHTTPHealthMonitor(hmType) {
+			payload.URLPath = healthMonitorURLPath
+			payload.HTTPMethod = healthMonitorMethod
+			payload.ExpectedCodes = healthMonitorExpectedStatusCode
+			payload.DomainName = healthMonitorDomainName
+			payload.HTTPVersion = settings.HTTPVersion
+		}
+		client, ctx := getApiClient(cmd)
 		healthMonitor, err := client.CloudLoadBalancer.HealthMonitors().Create(ctx, args[0], &payload)
 		if err != nil {
 			log.Fatal(err)
 		}
-		var data [][]string
-		data = append(data, []string{healthMonitor.ID, healthMonitor.Name, healthMonitor.Type,
-			strconv.Itoa(healthMonitor.Delay), strconv.Itoa(healthMonitor.TimeOut), strconv.Itoa(healthMonitor.MaxRetries),
-			healthMonitor.DomainName, healthMonitor.UrlPath})
-		formatter.Output(healthMonitorListHeader, data)
+		formatter.Output(healthMonitorListHeader, [][]string{healthMonitorRow(healthMonitor)})
 	},
 }
 
 var lbHealthMonitorUpdateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update health monitor of a listener",
-	Long: `Update health monitor of a listener with listener ID as input
-Example: bizfly loadbalancer listener update <health-monitor-id> --name sadjf --type HTTP --delay 10 --timeout 10 --max-retries 3 --domain-name www.google.com --url-path /`,
+	Long: `Update health monitor with health monitor ID as input. Only the given flags are changed.
+Example: bizfly loadbalancer health-monitor update <health-monitor-id> --delay 10 --timeout 10 --domain-name www.google.com --url-path /`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) > 1 {
 			fmt.Printf("Unknow variable %s", strings.Join(args[1:], ""))
 		}
 		client, ctx := getApiClient(cmd)
+		current, err := client.CloudLoadBalancer.HealthMonitors().Get(ctx, args[0])
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Health monitor %s not found.", args[0])
+				return
+			}
+			log.Fatal(err)
+		}
+		if err := validateHealthMonitorFlags(cmd, current.Type); err != nil {
+			log.Fatal(err)
+		}
+		// only the changed flags are sent, so other settings of the health monitor are kept
+		flags := cmd.Flags()
+		settings := healthMonitorSettings{
+			Type:          current.Type,
+			URLPath:       current.UrlPath,
+			ExpectedCodes: current.ExpectedCodes,
+			DomainName:    current.DomainName,
+			HTTPVersion:   current.HTTPVersion,
+		}
+		if flags.Changed("url-path") {
+			settings.URLPath = healthMonitorURLPath
+		}
+		if flags.Changed("expected-codes") {
+			settings.ExpectedCodes = healthMonitorExpectedStatusCode
+		}
+		if flags.Changed("domain-name") {
+			settings.DomainName = healthMonitorDomainName
+		}
+		if flags.Changed("http-version") {
+			settings.HTTPVersion = healthMonitorHTTPVersion
+		} else if flags.Changed("domain-name") && healthMonitorDomainName != "" && settings.HTTPVersion != 1.1 {
+			// like create, a domain name switches the health checks to HTTP 1.1
+			healthMonitorHTTPVersion = 1.1
+			settings.HTTPVersion = 1.1
+		}
+		if err := validateHealthMonitor(settings); err != nil {
+			log.Fatal(err)
+		}
 		payload := gobizfly.HealthMonitorUpdateRequest{
-			Name:           healthMonitorName,
-			Delay:          &healthMonitorDelay,
-			TimeOut:        &healthMonitorTimeout,
-			MaxRetries:     &healthMonitorMaxRetries,
-			URLPath:        &healthMonitorURLPath,
-			MaxRetriesDown: &healthMonitorMaxRetriesDown,
-			HTTPMethod:     &healthMonitorMethod,
-			ExpectedCodes:  &healthMonitorExpectedStatusCode,
+			Name: current.Name,
+		}
+		if flags.Changed("name") {
+			payload.Name = healthMonitorName
+		}
+		if flags.Changed("delay") {
+			payload.Delay = &healthMonitorDelay
+		}
+		if flags.Changed("timeout") {
+			payload.TimeOut = &healthMonitorTimeout
+		}
+		if flags.Changed("max-retries") {
+			payload.MaxRetries = &healthMonitorMaxRetries
+		}
+		if flags.Changed("max-retries-down") {
+			payload.MaxRetriesDown = &healthMonitorMaxRetriesDown
+		}
+		if flags.Changed("url-path") {
+			payload.URLPath = &healthMonitorURLPath
+		}
+		if flags.Changed("method") {
+			payload.HTTPMethod = &healthMonitorMethod
+		}
+		if flags.Changed("expected-codes") {
+			payload.ExpectedCodes = &healthMonitorExpectedStatusCode
+		}
+		if flags.Changed("domain-name") {
+			payload.DomainName = &healthMonitorDomainName
+		}
+		if settings.HTTPVersion != current.HTTPVersion {
+			payload.HTTPVersion = &healthMonitorHTTPVersion
 		}
 		healthMonitor, err := client.CloudLoadBalancer.HealthMonitors().Update(ctx, args[0], &payload)
 		if err != nil {
 			log.Fatal(err)
 		}
-		var data [][]string
-		data = append(data, []string{healthMonitor.ID, healthMonitor.Name, healthMonitor.Type,
-			strconv.Itoa(healthMonitor.Delay), strconv.Itoa(healthMonitor.TimeOut), strconv.Itoa(healthMonitor.MaxRetries),
-			healthMonitor.DomainName, healthMonitor.UrlPath})
-		formatter.Output(healthMonitorListHeader, data)
+		formatter.Output(healthMonitorListHeader, [][]string{healthMonitorRow(healthMonitor)})
 	},
 }
 
@@ -592,18 +720,445 @@ var lbResizeLoadBalancerCmd = &cobra.Command{
 	},
 }
 
+func healthMonitorRow(hm *gobizfly.HealthMonitor) []string {
+	httpVersion := ""
+	if hm.HTTPVersion != 0 {
+		httpVersion = strconv.FormatFloat(float64(hm.HTTPVersion), 'f', 1, 32)
+	}
+	return []string{hm.ID, hm.Name, hm.Type, strconv.Itoa(hm.Delay), strconv.Itoa(hm.MaxRetries),
+		strconv.Itoa(hm.TimeOut), hm.OperatingStatus, hm.DomainName, hm.UrlPath, httpVersion, hm.ExpectedCodes}
+}
+
+func isHTTPHealthMonitor(hmType string) bool {
+	return hmType == "HTTP" || hmType == "HTTPS"
+}
+
+// healthMonitorSettings contains the HTTP settings of a health monitor which depend on each other
+type healthMonitorSettings struct {
+	Type          string
+	URLPath       string
+	ExpectedCodes string
+	DomainName    string
+	HTTPVersion   float32
+}
+
+// validateHealthMonitorFlags rejects HTTP only flags for TCP, UDP-CONNECT, PING and TLS-HELLO health monitors
+func validateHealthMonitorFlags(cmd *cobra.Command, hmType string) error {
+	if isHTTPHealthMonitor(hmType) {
+		if cmd.Flags().Changed("http-version") && healthMonitorHTTPVersion != 1.0 && healthMonitorHTTPVersion != 1.1 {
+			return fmt.Errorf("invalid HTTP version %v, must be 1.0 or 1.1", healthMonitorHTTPVersion)
+		}
+		return nil
+	}
+	for _, name := range []string{"url-path", "method", "expected-codes", "domain-name", "http-version"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s can not be used with %s health monitor", name, hmType)
+		}
+	}
+	return nil
+}
+
+// validateHealthMonitor checks the settings a health monitor has after a create or an update.
+// An HTTP version 0 means the version is not set.
+func validateHealthMonitor(hm healthMonitorSettings) error {
+	if !isHTTPHealthMonitor(hm.Type) {
+		return nil
+	}
+	if hm.ExpectedCodes != "" {
+		if err := validateExpectedCodes(hm.ExpectedCodes); err != nil {
+			return err
+		}
+	}
+	if hm.HTTPVersion != 0 && hm.HTTPVersion != 1.0 && hm.HTTPVersion != 1.1 {
+		return fmt.Errorf("invalid HTTP version %v, must be 1.0 or 1.1", hm.HTTPVersion)
+	}
+	if hm.DomainName != "" && hm.HTTPVersion != 1.1 {
+		return errors.New("--domain-name needs HTTP version 1.1")
+	}
+	if hm.URLPath != "" && !strings.HasPrefix(hm.URLPath, "/") {
+		return fmt.Errorf("invalid URL path %s, must start with /", hm.URLPath)
+	}
+	return nil
+}
+
+// validateExpectedCodes checks the expected codes are a code (200), a list of codes (200,202) or a range (200-204)
+func validateExpectedCodes(codes string) error {
+	invalid := fmt.Errorf("invalid expected codes %q. Use a code (200), a list of codes (200,202) or a range (200-204)", codes)
+	parseCode := func(value string) (int, error) {
+		code, err := strconv.Atoi(value)
+		if err != nil || len(value) != 3 || code < 100 || code > 599 {
+			return 0, invalid
+		}
+		return code, nil
+	}
+	if strings.Contains(codes, "-") {
+		bounds := strings.Split(codes, "-")
+		if len(bounds) != 2 {
+			return invalid
+		}
+		low, err := parseCode(bounds[0])
+		if err != nil {
+			return err
+		}
+		high, err := parseCode(bounds[1])
+		if err != nil {
+			return err
+		}
+		if low > high {
+			return invalid
+		}
+		return nil
+	}
+	for _, code := range strings.Split(codes, ",") {
+		if _, err := parseCode(code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lbConfig is the layout of the config file used by loadbalancer create --from-file
+type lbConfig struct {
+	Name        string             `yaml:"name"`
+	Description string             `yaml:"description"`
+	Type        string             `yaml:"type"`
+	NetworkType string             `yaml:"network_type"`
+	NetworkID   string             `yaml:"network_id"`
+	Listeners   []lbListenerConfig `yaml:"listeners"`
+}
+
+type lbListenerConfig struct {
+	Name     string       `yaml:"name"`
+	Protocol string       `yaml:"protocol"`
+	Port     int          `yaml:"port"`
+	TLSRef   string       `yaml:"tls_ref"`
+	Pool     lbPoolConfig `yaml:"pool"`
+}
+
+type lbPoolConfig struct {
+	Name          string                 `yaml:"name"`
+	Algorithm     string                 `yaml:"algorithm"`
+	Protocol      string                 `yaml:"protocol"`
+	Members       []string               `yaml:"members"`
+	HealthMonitor *lbHealthMonitorConfig `yaml:"health_monitor"`
+}
+
+type lbHealthMonitorConfig struct {
+	Type           string `yaml:"type"`
+	URLPath        string `yaml:"url_path"`
+	Method         string `yaml:"method"`
+	ExpectedCodes  string `yaml:"expected_codes"`
+	Delay          int    `yaml:"delay"`
+	Timeout        int    `yaml:"timeout"`
+	MaxRetries     int    `yaml:"max_retries"`
+	MaxRetriesDown int    `yaml:"max_retries_down"`
+}
+
+// loadLoadBalancerConfig reads a load balancer create request and the pool members from a YAML file.
+// Fields which are not set in the file fall back to the flag values.
+func loadLoadBalancerConfig(path string) (gobizfly.LoadBalancerCreateRequest, []lbMemberSpec) {
+	fileBytes, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var cfg lbConfig
+	if err := yaml.UnmarshalStrict(fileBytes, &cfg); err != nil {
+		log.Fatalf("Invalid load balancer config file %s: %v", path, err)
+	}
+	payload := gobizfly.LoadBalancerCreateRequest{
+		Name:         stringOrDefault(cfg.Name, lbName),
+		Description:  stringOrDefault(cfg.Description, description),
+		Type:         stringOrDefault(cfg.Type, lbType),
+		NetworkType:  stringOrDefault(cfg.NetworkType, networkType),
+		VPCNetworkID: stringOrDefault(cfg.NetworkID, vpcNetworkId),
+	}
+	var members []lbMemberSpec
+	for _, l := range cfg.Listeners {
+		if l.Protocol == "" || l.Port == 0 {
+			log.Fatalf("Listener %q in config file %s needs a protocol and a port", l.Name, path)
+		}
+		listenerProtocol := strings.ToUpper(l.Protocol)
+		listener := newLoadBalancerListener(l.Name, listenerProtocol, l.Port, l.TLSRef, l.Pool.Name,
+			defaultHealthMonitorType(listenerProtocol))
+		pool := &listener.DefaultPool
+		pool.LbAlgorithm = stringOrDefault(l.Pool.Algorithm, pool.LbAlgorithm)
+		pool.Protocol = strings.ToUpper(stringOrDefault(l.Pool.Protocol, pool.Protocol))
+		for _, m := range l.Pool.Members {
+			address, port, err := parseMemberAddress(m)
+			if err != nil {
+				log.Fatalf("Invalid member %q of pool %s in config file %s: %v", m, pool.Name, path, err)
+			}
+			members = append(members, lbMemberSpec{Pool: pool.Name, Address: address, Port: port})
+		}
+		if hm := l.Pool.HealthMonitor; hm != nil {
+			pool.HealthMonitor.Type = strings.ToUpper(stringOrDefault(hm.Type, pool.HealthMonitor.Type))
+			pool.HealthMonitor.URLPath = stringOrDefault(hm.URLPath, pool.HealthMonitor.URLPath)
+			pool.HealthMonitor.HTTPMethod = stringOrDefault(hm.Method, pool.HealthMonitor.HTTPMethod)
+			pool.HealthMonitor.ExpectedCodes = stringOrDefault(hm.ExpectedCodes, pool.HealthMonitor.ExpectedCodes)
+			pool.HealthMonitor.Delay = intOrDefault(hm.Delay, pool.HealthMonitor.Delay)
+			pool.HealthMonitor.Timeout = intOrDefault(hm.Timeout, pool.HealthMonitor.Timeout)
+			pool.HealthMonitor.MaxRetries = intOrDefault(hm.MaxRetries, pool.HealthMonitor.MaxRetries)
+			pool.HealthMonitor.MaxRetriesDown = intOrDefault(hm.MaxRetriesDown, pool.HealthMonitor.MaxRetriesDown)
+		}
+		clearHTTPHealthMonitorFields(&pool.HealthMonitor)
+		payload.Listeners = append(payload.Listeners, listener)
+	}
+	return payload, members
+}
+
+// newLoadBalancerListener builds a listener with a default pool and health monitor from the flag values.
+// The pool is created without members, they are added with the member API once the load balancer is active.
+func newLoadBalancerListener(name, listenerProtocol string, port int, tlsRef, poolName,
+	hmType string) gobizfly.LoadBalancerListener {
+	if name == "" {
+		name = fmt.Sprintf("%s-%d", strings.ToLower(listenerProtocol), port)
+	}
+	if poolName == "" {
+		poolName = fmt.Sprintf("pool-%d", port)
+	}
+	listener := gobizfly.LoadBalancerListener{
+		Name:          name,
+		Protocol:      listenerProtocol,
+		DefaultTLSRef: tlsRef,
+		ProtocolPort:  port,
+		DefaultPool: gobizfly.ListenerPool{
+			LbAlgorithm: lbAlgorithm,
+			Name:        poolName,
+			Protocol:    poolProtocolOf(listenerProtocol),
+			Members:     []string{},
+			HealthMonitor: gobizfly.ListenerHealthMonitor{
+				Delay:          healthMonitorDelay,
+				MaxRetries:     healthMonitorMaxRetries,
+				Timeout:        healthMonitorTimeout,
+				ExpectedCodes:  healthMonitorExpectedStatusCode,
+				URLPath:        healthMonitorURLPath,
+				MaxRetriesDown: healthMonitorMaxRetriesDown,
+				Type:           strings.ToUpper(hmType),
+				HTTPMethod:     healthMonitorMethod,
+			},
+		},
+	}
+	clearHTTPHealthMonitorFields(&listener.DefaultPool.HealthMonitor)
+	return listener
+}
+
+// parseLoadBalancerListener parses a listener in the protocol:port[:pool-name][:tls-ref] format.
+// The TLS reference is the rest of the string so it can contain colons. An empty health monitor type
+// is the default type for the listener protocol.
+func parseLoadBalancerListener(spec, hmType string) (gobizfly.LoadBalancerListener, error) {
+	parts := strings.SplitN(spec, ":", 4)
+	if len(parts) < 2 || parts[0] == "" {
+		return gobizfly.LoadBalancerListener{}, fmt.Errorf("invalid listener %q. Use protocol:port[:pool-name][:tls-ref]", spec)
+	}
+	port, err := strconv.Atoi(parts[1])
+	if err != nil || port < 1 || port > 65535 {
+		return gobizfly.LoadBalancerListener{}, fmt.Errorf("invalid port of listener %q", spec)
+	}
+	var poolName, ref string
+	if len(parts) > 2 {
+		poolName = parts[2]
+	}
+	if len(parts) > 3 {
+		ref = parts[3]
+	}
+	listenerProtocol := strings.ToUpper(parts[0])
+	if hmType == "" {
+		hmType = defaultHealthMonitorType(listenerProtocol)
+	}
+	return newLoadBalancerListener("", listenerProtocol, port, ref, poolName, hmType), nil
+}
+
+// defaultHealthMonitorType returns the health monitor type matching the protocol of a listener
+func defaultHealthMonitorType(listenerProtocol string) string {
+	switch listenerProtocol {
+	case "TCP":
+		return "TCP"
+	case "UDP":
+		return "UDP-CONNECT"
+	case "HTTPS":
+		return "HTTPS"
+	default:
+		return "HTTP"
+	}
+}
+
+// clearHTTPHealthMonitorFields clears the HTTP only fields of TCP, UDP-CONNECT, PING and TLS-HELLO health monitors
+func clearHTTPHealthMonitorFields(hm *gobizfly.ListenerHealthMonitor) {
+	if isHTTPHealthMonitor(hm.Type) {
+		return
+	}
+	hm.URLPath = ""
+	hm.HTTPMethod = ""
+	hm.ExpectedCodes = ""
+}
+
+// lbMemberSpec is a member of a default pool given by pool-name=address:port
+type lbMemberSpec struct {
+	Pool    string
+	Address string
+	Port    int
+}
+
+// parseLoadBalancerMember parses a member in the pool-name=address:port format
+func parseLoadBalancerMember(spec string) (lbMemberSpec, error) {
+	parts := strings.SplitN(spec, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return lbMemberSpec{}, fmt.Errorf("invalid member %q. Use pool-name=address:port", spec)
+	}
+	address, port, err := parseMemberAddress(parts[1])
+	if err != nil {
+		return lbMemberSpec{}, fmt.Errorf("invalid member %q: %v", spec, err)
+	}
+	return lbMemberSpec{Pool: parts[0], Address: address, Port: port}, nil
+}
+
+// parseMemberAddress parses an address:port with an IPv4 address or an IPv6 address between brackets
+func parseMemberAddress(value string) (string, int, error) {
+	host, portValue, err := net.SplitHostPort(value)
+	if err != nil {
+		return "", 0, errors.New("use address:port, e.g. 10.20.1.5:8080")
+	}
+	if net.ParseIP(host) == nil {
+		return "", 0, fmt.Errorf("%s is not an IP address", host)
+	}
+	port, err := strconv.Atoi(portValue)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %s", portValue)
+	}
+	return host, port, nil
+}
+
+// checkLoadBalancerMembers checks every member belongs to the default pool of a listener
+func checkLoadBalancerMembers(listeners []gobizfly.LoadBalancerListener, members []lbMemberSpec) error {
+	for _, m := range members {
+		found := false
+		for _, l := range listeners {
+			if l.DefaultPool.Name == m.Pool {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("pool %s of member %s:%d is not a default pool of any listener", m.Pool, m.Address, m.Port)
+		}
+	}
+	return nil
+}
+
+// addLoadBalancerMembers waits until the new load balancer is active and adds the members to its pools.
+// The load balancer is busy after each change, so the members are added one at a time.
+func addLoadBalancerMembers(ctx context.Context, client *gobizfly.Client, lbID string, members []lbMemberSpec) error {
+	if err := waitLoadBalancerActive(ctx, client, lbID); err != nil {
+		return err
+	}
+	pools, err := client.CloudLoadBalancer.Pools().List(ctx, lbID, &gobizfly.ListOptions{})
+	if err != nil {
+		return err
+	}
+	poolIDs := make(map[string]string)
+	for _, pool := range pools {
+		poolIDs[pool.Name] = pool.ID
+	}
+	for _, m := range members {
+		poolID, ok := poolIDs[m.Pool]
+		if !ok {
+			return fmt.Errorf("pool %s not found in load balancer %s", m.Pool, lbID)
+		}
+		member, err := createMember(ctx, client, poolID, &memberCreateRequest{
+			Name:         fmt.Sprintf("%s-%s-%d", m.Pool, m.Address, m.Port),
+			Weight:       1,
+			Address:      m.Address,
+			ProtocolPort: m.Port,
+		})
+		if err != nil {
+			return fmt.Errorf("add member %s:%d to pool %s: %v", m.Address, m.Port, m.Pool, err)
+		}
+		fmt.Printf("Added member %s (%s:%d) to pool %s\n", member.ID, m.Address, m.Port, m.Pool)
+		if err := waitLoadBalancerActive(ctx, client, lbID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitLoadBalancerActive waits until the provisioning status of the load balancer is ACTIVE
+func waitLoadBalancerActive(ctx context.Context, client *gobizfly.Client, lbID string) error {
+	deadline := time.Now().Add(lbWaitTimeout)
+	for {
+		lb, err := client.CloudLoadBalancer.Get(ctx, lbID)
+		if err == nil {
+			switch lb.ProvisioningStatus {
+			case lbStatusActive:
+				return nil
+			case lbStatusError:
+				return fmt.Errorf("load balancer %s is in ERROR status", lbID)
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for load balancer %s to be ACTIVE", lbID)
+		}
+		time.Sleep(lbPollInterval)
+	}
+}
+
+// validateLoadBalancerListeners checks the listeners do not share a port or a default pool name
+func validateLoadBalancerListeners(listeners []gobizfly.LoadBalancerListener) {
+	ports := make(map[int]bool)
+	pools := make(map[string]bool)
+	for _, l := range listeners {
+		if ports[l.ProtocolPort] {
+			log.Fatalf("More than one listener uses port %d", l.ProtocolPort)
+		}
+		ports[l.ProtocolPort] = true
+		if pools[l.DefaultPool.Name] {
+			log.Fatalf("More than one listener uses pool name %s", l.DefaultPool.Name)
+		}
+		pools[l.DefaultPool.Name] = true
+		if l.Protocol == "TERMINATED_HTTPS" && l.DefaultTLSRef == "" {
+			log.Fatalf("Listener %s with protocol TERMINATED_HTTPS needs a TLS reference", l.Name)
+		}
+		hm := l.DefaultPool.HealthMonitor
+		if isHTTPHealthMonitor(hm.Type) && hm.ExpectedCodes != "" {
+			if err := validateExpectedCodes(hm.ExpectedCodes); err != nil {
+				log.Fatalf("Health monitor of listener %s: %v", l.Name, err)
+			}
+		}
+	}
+}
+
+// poolProtocolOf returns the protocol of the backend pool for a listener protocol
+func poolProtocolOf(listenerProtocol string) string {
+	if listenerProtocol == "TERMINATED_HTTPS" {
+		return "HTTP"
+	}
+	return listenerProtocol
+}
+
+func stringOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func intOrDefault(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
 func init() {
 	rootCmd.AddCommand(lbCmd)
 	lbCmd.AddCommand(lbListCmd)
 	lbCmd.AddCommand(lbGetCmd)
 	lbCmd.AddCommand(lbDeleteCmd)
 	lbCmd.AddCommand(lbResizeLoadBalancerCmd)
+	addEstimateFlags(lbCreateCmd)
 	lbCmd.AddCommand(lbCreateCmd)
 	lcpf := lbCreateCmd.PersistentFlags()
 	lcpf.StringVar(&lbName, "name", "", "Name of the load balancer")
 	lcpf.StringVar(&description, "description", "", "Description of the load balancer")
 	lcpf.StringVar(&lbType, "type", "medium", "Type of the load balancer (small, medium, large)")
-	_ = cobra.MarkFlagRequired(lcpf, "name")
 	lcpf.StringVar(&networkType, "network-type", "external", "Type of the network (external, internal)")
 	lcpf.StringVar(&vpcNetworkId, "network-id", "", "ID of the network")
 	lcpf.StringVar(&listenerName, "listener-name", "Default Listener", "Name of the listener")
@@ -620,6 +1175,11 @@ func init() {
 	lcpf.StringVar(&healthMonitorURLPath, "health-monitor-url-path", "/", "URL path of the health monitor")
 	lcpf.IntVar(&healthMonitorMaxRetriesDown, "max-retries-down", 3, "Max retries down of the health monitor")
 	lcpf.StringVar(&healthMonitorMethod, "health-monitor-method", "GET", "Method of the health monitor")
+	lcpf.StringArrayVar(&lbListeners, "listener", []string{}, "Listener in the protocol:port[:pool-name][:tls-ref] format. "+
+		"Can be repeated. Overrides --listener-name, --listener-protocol, --listener-port, --pool-name and --tls-ref")
+	lcpf.StringArrayVar(&lbMembers, "member", []string{}, "Member of a default pool in the pool-name=address:port format. Can be repeated")
+	lcpf.DurationVar(&lbWaitTimeout, "wait-timeout", 10*time.Minute, "Maximum time to wait for the load balancer before adding the members")
+	lcpf.StringVar(&lbConfigFile, "from-file", "", "Create the load balancer from a YAML config file")
 
 	lbCmd.AddCommand(lbPoolCmd)
 	lbPoolCmd.AddCommand(lbPoolGetCmd)
@@ -662,7 +1222,7 @@ func init() {
 	lhcf := lbHealthMonitorCreateCmd.PersistentFlags()
 	lhcf.StringVar(&healthMonitorName, "name", "", "Name of the health monitor")
 	_ = cobra.MarkFlagRequired(lhcf, "name")
-	lhcf.StringVar(&healthMonitorProtocol, "type", "HTTP", "Type of the health monitor (HTTP, HTTPS, UDP, TCP)")
+	lhcf.StringVar(&healthMonitorProtocol, "type", "HTTP", "Type of the health monitor (HTTP, HTTPS, TCP, UDP-CONNECT, PING, TLS-HELLO)")
 	lhcf.IntVar(&healthMonitorDelay, "delay", 5, "Delay of the health monitor")
 	lhcf.IntVar(&healthMonitorTimeout, "timeout", 5, "Timeout of the health monitor")
 	lhcf.IntVar(&healthMonitorMaxRetries, "max-retries", 3, "Max retries of the health monitor")
@@ -670,10 +1230,13 @@ func init() {
 	lhcf.StringVar(&healthMonitorExpectedStatusCode, "expected-codes", "", "Expected codes of the health monitor")
 	lhcf.StringVar(&healthMonitorMethod, "method", "GET", "Method of the health monitor")
 	lhcf.IntVar(&healthMonitorMaxRetriesDown, "max-retries-down", 3, "Max retries down of the health monitor")
+	lhcf.StringVar(&healthMonitorDomainName, "domain-name", "", "Domain name sent in the Host header of HTTP health checks")
+	lhcf.Float32Var(&healthMonitorHTTPVersion, "http-version", 1.0, "HTTP version of the health checks (1.0, 1.1). Default is 1.1 with --domain-name")
 	lbHealthMonitorCmd.AddCommand(lbHealthMonitorUpdateCmd)
 	lhuf := lbHealthMonitorUpdateCmd.PersistentFlags()
 	lhuf.StringVar(&healthMonitorName, "name", "", "Name of the health monitor")
 	lhuf.StringVar(&healthMonitorProtocol, "type", "HTTP", "Type of the health monitor (HTTP, HTTPS, UDP, TCP)")
+	_ = lhuf.MarkDeprecated("type", "the type of a health monitor can not be updated")
 	lhuf.IntVar(&healthMonitorDelay, "delay", 5, "Delay of the health monitor")
 	lhuf.IntVar(&healthMonitorTimeout, "timeout", 5, "Timeout of the health monitor")
 	lhuf.IntVar(&healthMonitorMaxRetries, "max-retries", 3, "Max retries of the health monitor")
@@ -681,4 +1244,6 @@ func init() {
 	lhuf.StringVar(&healthMonitorExpectedStatusCode, "expected-codes", "", "Expected codes of the health monitor")
 	lhuf.StringVar(&healthMonitorMethod, "method", "GET", "Method of the health monitor")
 	lhuf.IntVar(&healthMonitorMaxRetriesDown, "max-retries-down", 3, "Max retries down of the health monitor")
+	lhuf.StringVar(&healthMonitorDomainName, "domain-name", "", "Domain name sent in the Host header of HTTP health checks")
+	lhuf.Float32Var(&healthMonitorHTTPVersion, "http-version", 1.0, "HTTP version of the health checks (1.0, 1.1)")
 }
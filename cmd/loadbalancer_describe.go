@@ -0,0 +1,227 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+	"github.com/spf13/cobra"
+)
+
+var lbDescribeOutput string
+
+// lbTopology contains a load balancer with its listeners, pools, members and health monitors
+type lbTopology struct {
+	LoadBalancer *gobizfly.LoadBalancer `json:"loadbalancer"`
+	Listeners    []*lbListenerTopology  `json:"listeners"`
+	// Pools which are not the default pool of any listener
+	OtherPools []*lbPoolTopology `json:"other_pools"`
+}
+
+type lbListenerTopology struct {
+	Listener    *gobizfly.Listener `json:"listener"`
+	DefaultPool *lbPoolTopology    `json:"default_pool"`
+}
+
+type lbPoolTopology struct {
+	Pool          *gobizfly.Pool          `json:"pool"`
+	Members       []*gobizfly.Member      `json:"members"`
+	HealthMonitor *gobizfly.HealthMonitor `json:"health_monitor"`
+}
+
+// lbDescribeCmd represents the load balancer describe command
+var lbDescribeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "Describe the topology of a load balancer",
+	Long: `Describe a load balancer with its listeners, default pools, members and health monitors as a tree
+Example: bizfly loadbalancer describe fd554aac-9ab1-11ea-b09d-bbaf82f02f58
+Example: bizfly loadbalancer describe fd554aac-9ab1-11ea-b09d-bbaf82f02f58 --output json
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify loadbalancer-id in the command. Use bizfly loadbalancer describe <loadbalancer-id>")
+			os.Exit(1)
+		}
+		if lbDescribeOutput != "tree" && lbDescribeOutput != "json" {
+			fmt.Printf("Invalid output format %s. Use tree or json\n", lbDescribeOutput)
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		topology, err := getLoadBalancerTopology(ctx, client, args[0])
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Load Balancer %s not found.", args[0])
+				return
+			}
+			log.Fatal(err)
+		}
+		if lbDescribeOutput == "json" {
+			if err := formatter.JSONOutput(topology); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		formatter.TreeOutput(topology.tree())
+	},
+}
+
+// getLoadBalancerTopology fetches the load balancer, listeners, pools, members and health monitors concurrently
+func getLoadBalancerTopology(ctx context.Context, client *gobizfly.Client, lbID string) (*lbTopology, error) {
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		firstErr  error
+		lb        *gobizfly.LoadBalancer
+		listeners []*gobizfly.Listener
+		pools     []*gobizfly.Pool
+	)
+	setErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		var err error
+		if lb, err = client.CloudLoadBalancer.Get(ctx, lbID); err != nil {
+			setErr(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		if listeners, err = client.CloudLoadBalancer.Listeners().List(ctx, lbID, &gobizfly.ListOptions{}); err != nil {
+			setErr(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		if pools, err = client.CloudLoadBalancer.Pools().List(ctx, lbID, &gobizfly.ListOptions{}); err != nil {
+			setErr(err)
+		}
+	}()
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	poolTopologies := make(map[string]*lbPoolTopology, len(pools))
+	for _, pool := range pools {
+		pt := &lbPoolTopology{Pool: pool}
+		poolTopologies[pool.ID] = pt
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			members, err := client.CloudLoadBalancer.Members().List(ctx, pt.Pool.ID, &gobizfly.ListOptions{})
+			if err != nil {
+				setErr(err)
+				return
+			}
+			pt.Members = members
+		}()
+		if pool.HealthMonitorID != "" {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				hm, err := client.CloudLoadBalancer.HealthMonitors().Get(ctx, pt.Pool.HealthMonitorID)
+				if err != nil {
+					setErr(err)
+					return
+				}
+				pt.HealthMonitor = hm
+			}()
+		}
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	topology := &lbTopology{LoadBalancer: lb}
+	defaultPools := make(map[string]bool)
+	for _, listener := range listeners {
+		lt := &lbListenerTopology{Listener: listener}
+		if pt, ok := poolTopologies[listener.DefaultPoolID]; ok {
+			lt.DefaultPool = pt
+			defaultPools[listener.DefaultPoolID] = true
+		}
+		topology.Listeners = append(topology.Listeners, lt)
+	}
+	for _, pool := range pools {
+		if !defaultPools[pool.ID] {
+			topology.OtherPools = append(topology.OtherPools, poolTopologies[pool.ID])
+		}
+	}
+	return topology, nil
+}
+
+func (t *lbTopology) tree() *formatter.TreeNode {
+	lb := t.LoadBalancer
+	root := &formatter.TreeNode{Text: fmt.Sprintf("Load Balancer %s (%s) %s %s %s", lb.Name, lb.ID, lb.VipAddress,
+		lb.Type, lb.OperatingStatus)}
+	for _, lt := range t.Listeners {
+		l := lt.Listener
+		node := &formatter.TreeNode{Text: fmt.Sprintf("Listener %s (%s) %s:%d %s", l.Name, l.ID, l.Protocol,
+			l.ProtocolPort, l.OperatingStatus)}
+		if lt.DefaultPool != nil {
+			node.Children = append(node.Children, lt.DefaultPool.tree("Default Pool"))
+		}
+		root.Children = append(root.Children, node)
+	}
+	for _, pt := range t.OtherPools {
+		root.Children = append(root.Children, pt.tree("Pool"))
+	}
+	return root
+}
+
+func (t *lbPoolTopology) tree(title string) *formatter.TreeNode {
+	p := t.Pool
+	node := &formatter.TreeNode{Text: fmt.Sprintf("%s %s (%s) %s %s %s", title, p.Name, p.ID, p.Protocol,
+		p.LBAlgorithm, p.OperatingStatus)}
+	for _, m := range t.Members {
+		text := fmt.Sprintf("Member %s (%s) %s:%d weight %d %s", m.Name, m.ID, m.Address, m.ProtocolPort, m.Weight,
+			m.OperatingStatus)
+		if m.Backup {
+			text += " backup"
+		}
+		node.Children = append(node.Children, &formatter.TreeNode{Text: text})
+	}
+	if hm := t.HealthMonitor; hm != nil {
+		text := fmt.Sprintf("Health Monitor %s (%s) %s delay %ds timeout %ds max retries %d", hm.Name, hm.ID, hm.Type,
+			hm.Delay, hm.TimeOut, hm.MaxRetries)
+		if hm.UrlPath != "" {
+			text += fmt.Sprintf(" %s %s expect %s", hm.HTTPMethod, hm.UrlPath, hm.ExpectedCodes)
+		}
+		node.Children = append(node.Children, &formatter.TreeNode{Text: text + " " + hm.OperatingStatus})
+	}
+	return node
+}
+
+func init() {
+	lbCmd.AddCommand(lbDescribeCmd)
+	lbDescribeCmd.PersistentFlags().StringVarP(&lbDescribeOutput, "output", "o", "tree", "Output format (tree, json)")
+}
@@ -0,0 +1,275 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+const drainStateFileName = ".bizfly_drained_members.json"
+
+var (
+	drainPeriod       time.Duration
+	drainServerID     string
+	undrainWeight     int
+	drainStateHeaders = []string{"Pool ID", "Member ID", "Name", "Address", "Weight", "Original Weight"}
+)
+
+// drainedMember records the weight of a member before it was drained
+type drainedMember struct {
+	Weight    int    `json:"weight"`
+	DrainedAt string `json:"drained_at"`
+}
+
+// poolMember is a member with the ID of the pool it belongs to
+type poolMember struct {
+	PoolID string
+	Member *gobizfly.Member
+}
+
+// lbMemberDrainCmd represents the member drain command
+var lbMemberDrainCmd = &cobra.Command{
+	Use:   "drain",
+	Short: "Drain members of a pool for maintenance",
+	Long: `Drain a member by setting its weight to 0, so it receives no new connections.
+The member is given by ID, name or address. The original weight is recorded locally and restored by 'member undrain'.
+With --server, the server is drained from every pool it belongs to across all load balancers.
+Example: bizfly loadbalancer member drain <pool_id> <member> --drain-period 2m
+Example: bizfly loadbalancer member drain --server <server_id>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, ctx := getApiClient(cmd)
+		members := resolveDrainMembers(ctx, client, args, "drain")
+		state := loadDrainState()
+		var data [][]string
+		for _, pm := range members {
+			m := pm.Member
+			key := drainStateKey(pm.PoolID, m.ID)
+			if _, ok := state[key]; ok && m.Weight == 0 {
+				fmt.Printf("Member %s of pool %s is already drained\n", m.ID, pm.PoolID)
+				continue
+			}
+			if m.Weight == 0 {
+				fmt.Printf("Member %s of pool %s already has weight 0 and no recorded weight, it is not drained. "+
+					"Use bizfly loadbalancer member update --weight to change its weight\n", m.ID, pm.PoolID)
+				continue
+			}
+			state[key] = drainedMember{Weight: m.Weight, DrainedAt: time.Now().Format(time.RFC3339)}
+			updated, err := updateMember(ctx, client, pm.PoolID, m.ID, &memberUpdateRequest{
+				Name:   m.Name,
+				Weight: 0,
+				Backup: m.Backup,
+			})
+			if err != nil {
+				delete(state, key)
+				saveDrainState(state)
+				log.Fatalf("Drain member %s of pool %s error: %v", m.ID, pm.PoolID, err)
+			}
+			saveDrainState(state)
+			data = append(data, []string{pm.PoolID, updated.ID, updated.Name, updated.Address, "0", fmt.Sprint(m.Weight)})
+		}
+		formatter.Output(drainStateHeaders, data)
+		if drainPeriod > 0 && len(data) > 0 {
+			fmt.Printf("Waiting %s for connections to drain\n", drainPeriod)
+			time.Sleep(drainPeriod)
+			fmt.Println("Drain period is over")
+		}
+	},
+}
+
+// lbMemberUndrainCmd represents the member undrain command
+var lbMemberUndrainCmd = &cobra.Command{
+	Use:   "undrain",
+	Short: "Restore drained members of a pool",
+	Long: `Restore the weight a member had before 'member drain'. The member is given by ID, name or address.
+With --server, the server is restored in every pool it belongs to across all load balancers.
+Example: bizfly loadbalancer member undrain <pool_id> <member>
+Example: bizfly loadbalancer member undrain --server <server_id>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cmd.Flags().Changed("weight") && (undrainWeight < 1 || undrainWeight > maxMemberWeight) {
+			fmt.Printf("Invalid weight %d. The weight is between 1 and %d\n", undrainWeight, maxMemberWeight)
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		members := resolveDrainMembers(ctx, client, args, "undrain")
+		state := loadDrainState()
+		var data [][]string
+		for _, pm := range members {
+			m := pm.Member
+			key := drainStateKey(pm.PoolID, m.ID)
+			weight := undrainWeight
+			if !cmd.Flags().Changed("weight") {
+				recorded, ok := state[key]
+				if !ok || recorded.Weight == 0 {
+					fmt.Printf("No recorded weight of member %s of pool %s, use --weight to restore it\n", m.ID, pm.PoolID)
+					continue
+				}
+				weight = recorded.Weight
+			}
+			updated, err := updateMember(ctx, client, pm.PoolID, m.ID, &memberUpdateRequest{
+				Name:   m.Name,
+				Weight: weight,
+				Backup: m.Backup,
+			})
+			if err != nil {
+				log.Fatalf("Undrain member %s of pool %s error: %v", m.ID, pm.PoolID, err)
+			}
+			delete(state, key)
+			saveDrainState(state)
+			data = append(data, []string{pm.PoolID, updated.ID, updated.Name, updated.Address,
+				fmt.Sprint(updated.Weight), fmt.Sprint(weight)})
+		}
+		formatter.Output(drainStateHeaders, data)
+	},
+}
+
+// resolveDrainMembers returns the members given by <pool-id> <member> or by --server
+func resolveDrainMembers(ctx context.Context, client *gobizfly.Client, args []string, action string) []poolMember {
+	if drainServerID != "" {
+		if len(args) > 0 {
+			fmt.Printf("Unknow variable %s", strings.Join(args, ""))
+		}
+		members, err := serverPoolMembers(ctx, client, drainServerID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(members) == 0 {
+			fmt.Printf("Server %s is not a member of any pool\n", drainServerID)
+			os.Exit(1)
+		}
+		return members
+	}
+	if len(args) < 2 {
+		fmt.Printf("You need to specify pool-id and member in the command. Use bizfly loadbalancer member %s <pool-id> <member>\n", action)
+		os.Exit(1)
+	}
+	members, err := client.CloudLoadBalancer.Members().List(ctx, args[0], &gobizfly.ListOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	var result []poolMember
+	for _, member := range members {
+		if member.ID == args[1] || member.Name == args[1] || member.Address == args[1] {
+			result = append(result, poolMember{PoolID: args[0], Member: member})
+		}
+	}
+	if len(result) == 0 {
+		fmt.Printf("Member %s not found in pool %s.\n", args[1], args[0])
+		os.Exit(1)
+	}
+	if len(result) > 1 {
+		fmt.Printf("More than one member of pool %s matches %s, use the member ID\n", args[0], args[1])
+		os.Exit(1)
+	}
+	return result
+}
+
+// serverPoolMembers returns the members which have one of the LAN IPs of the server in all load balancers
+func serverPoolMembers(ctx context.Context, client *gobizfly.Client, serverID string) ([]poolMember, error) {
+	server, err := client.CloudServer.Get(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	var addresses []string
+	for _, lan := range server.IPAddresses.LanAddresses {
+		addresses = append(addresses, lan.Address)
+	}
+	lbs, err := client.CloudLoadBalancer.List(ctx, &gobizfly.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var result []poolMember
+	for _, lb := range lbs {
+		pools, err := client.CloudLoadBalancer.Pools().List(ctx, lb.ID, &gobizfly.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, pool := range pools {
+			members, err := client.CloudLoadBalancer.Members().List(ctx, pool.ID, &gobizfly.ListOptions{})
+			if err != nil {
+				return nil, err
+			}
+			for _, member := range members {
+				if _, ok := SliceContains(addresses, member.Address); ok {
+					result = append(result, poolMember{PoolID: pool.ID, Member: member})
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+func drainStateKey(poolID, memberID string) string {
+	return poolID + "/" + memberID
+}
+
+func drainStatePath() string {
+	home, err := homedir.Dir()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return filepath.Join(home, drainStateFileName)
+}
+
+func loadDrainState() map[string]drainedMember {
+	state := make(map[string]drainedMember)
+	data, err := os.ReadFile(drainStatePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state
+		}
+		log.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Fatalf("Invalid drain state file %s: %v", drainStatePath(), err)
+	}
+	return state
+}
+
+func saveDrainState(state map[string]drainedMember) {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(drainStatePath(), data, 0600); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func init() {
+	lbMemberCmd.AddCommand(lbMemberDrainCmd)
+	mdpf := lbMemberDrainCmd.PersistentFlags()
+	mdpf.DurationVar(&drainPeriod, "drain-period", 0, "Period to wait for connections to drain, e.g. 2m")
+	mdpf.StringVar(&drainServerID, "server", "", "ID of the server to drain from every pool")
+
+	lbMemberCmd.AddCommand(lbMemberUndrainCmd)
+	mupf := lbMemberUndrainCmd.PersistentFlags()
+	mupf.StringVar(&drainServerID, "server", "", "ID of the server to restore in every pool")
+	mupf.IntVar(&undrainWeight, "weight", 1, "Weight to restore instead of the recorded weight")
+}
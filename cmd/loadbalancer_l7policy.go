@@ -0,0 +1,500 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+	"github.com/spf13/cobra"
+)
+
+var (
+	l7PolicyListHeader = []string{"ID", "Name", "Action", "Position", "Redirect Pool ID", "Redirect URL",
+		"Operating Status"}
+	l7RuleListHeader       = []string{"ID", "Type", "Compare Type", "Key", "Value", "Invert", "Operating Status"}
+	l7PolicyActions        = []string{"REDIRECT_TO_POOL", "REDIRECT_TO_URL", "REJECT"}
+	l7RuleTypes            = []string{"PATH", "HOST_NAME", "HEADER", "COOKIE", "FILE_TYPE"}
+	l7RuleCompareTypes     = []string{"REGEX", "STARTS_WITH", "ENDS_WITH", "CONTAINS", "EQUAL_TO"}
+	l7PolicyName           string
+	l7PolicyAction         string
+	l7PolicyPosition       int
+	l7PolicyRedirectPoolID string
+	l7PolicyRedirectURL    string
+	l7PolicyRules          []string
+	l7RuleType             string
+	l7RuleCompareType      string
+	l7RuleKey              string
+	l7RuleValue            string
+	l7RuleInvert           bool
+)
+
+var lbL7PolicyCmd = &cobra.Command{
+	Use:   "l7policy",
+	Short: "Bizfly Cloud Load Balancer Layer 7 Policy Interaction",
+	Long:  "Bizfly Cloud Load Balancer Layer 7 Policy Action: Create, List, Get, Delete",
+	Run:   func(cmd *cobra.Command, args []string) {},
+}
+
+var lbL7RuleCmd = &cobra.Command{
+	Use:   "l7rule",
+	Short: "Bizfly Cloud Load Balancer Layer 7 Rule Interaction",
+	Long:  "Bizfly Cloud Load Balancer Layer 7 Rule Action: Create, List, Delete",
+	Run:   func(cmd *cobra.Command, args []string) {},
+}
+
+// lbL7PolicyCreateCmd represents the l7 policy create command
+var lbL7PolicyCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a layer 7 policy in a listener",
+	Long: `Create a layer 7 policy in a listener with listener ID as input.
+Action is one of REDIRECT_TO_POOL, REDIRECT_TO_URL or REJECT. Rules are given in the
+type=<type>,compare-type=<compare-type>,value=<value>[,key=<key>][,invert=true] format and can be repeated.
+Example: bizfly loadbalancer l7policy create <listener_id> --name api --action REDIRECT_TO_POOL --redirect-pool-id <pool_id> --position 1 --rule type=PATH,compare-type=STARTS_WITH,value=/api
+Example: bizfly loadbalancer l7policy create <listener_id> --name old-site --action REDIRECT_TO_URL --redirect-url https://example.com --rule type=HOST_NAME,compare-type=EQUAL_TO,value=old.example.com
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify listener-id in the command. Use bizfly loadbalancer l7policy create <listener-id>")
+			os.Exit(1)
+		}
+		if len(args) > 1 {
+			fmt.Printf("Unknow variable %s", strings.Join(args[1:], ""))
+		}
+		action := strings.ToUpper(l7PolicyAction)
+		validateL7PolicyAction(action, l7PolicyRedirectPoolID, l7PolicyRedirectURL)
+		if l7PolicyPosition < 1 {
+			fmt.Println("Position of the policy must be greater than 0")
+			os.Exit(1)
+		}
+		rules := make([]gobizfly.L7PolicyRuleRequest, 0)
+		for _, rule := range l7PolicyRules {
+			rules = append(rules, parseL7Rule(rule))
+		}
+		client, ctx := getApiClient(cmd)
+		policy, err := client.CloudLoadBalancer.L7Policies().Create(ctx, args[0], &gobizfly.CreateL7PolicyRequest{
+			Name:           l7PolicyName,
+			Description:    description,
+			Action:         action,
+			Position:       strconv.Itoa(l7PolicyPosition),
+			RedirectPoolId: l7PolicyRedirectPoolID,
+			RedirectUrl:    l7PolicyRedirectURL,
+			Rules:          rules,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		formatter.Output(l7PolicyListHeader, [][]string{l7PolicyRow(policy)})
+	},
+}
+
+// lbL7PolicyListCmd represents the l7 policy list command
+var lbL7PolicyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all layer 7 policies in a listener",
+	Long: `List all layer 7 policies in a listener, ordered by position
+Example: bizfly loadbalancer l7policy list <listener_id>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify listener-id in the command. Use bizfly loadbalancer l7policy list <listener-id>")
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		listener, err := client.CloudLoadBalancer.Listeners().Get(ctx, args[0])
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Listener %s not found.", args[0])
+				return
+			}
+			log.Fatal(err)
+		}
+		ids := make([]string, 0, len(listener.L7Policies))
+		for _, p := range listener.L7Policies {
+			ids = append(ids, p.ID)
+		}
+		policies, err := getL7Policies(ctx, client, ids)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var data [][]string
+		for _, policy := range policies {
+			data = append(data, l7PolicyRow(policy))
+		}
+		formatter.Output(l7PolicyListHeader, data)
+	},
+}
+
+// getL7Policies fetches the layer 7 policies concurrently and sorts them by position
+func getL7Policies(ctx context.Context, client *gobizfly.Client, ids []string) ([]*gobizfly.DetailL7Policy, error) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	policies := make([]*gobizfly.DetailL7Policy, len(ids))
+	for i, id := range ids {
+		i, id := i, id
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			policy, err := client.CloudLoadBalancer.L7Policies().Get(ctx, id)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				return
+			}
+			policies[i] = policy
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	sortL7Policies(policies)
+	return policies, nil
+}
+
+func sortL7Policies(policies []*gobizfly.DetailL7Policy) {
+	sort.SliceStable(policies, func(i, j int) bool {
+		return policies[i].Position < policies[j].Position
+	})
+}
+
+// lbL7PolicyGetCmd represents the l7 policy get command
+var lbL7PolicyGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Get a layer 7 policy",
+	Long: `Get detail a layer 7 policy with policy ID as input
+Example: bizfly loadbalancer l7policy get <policy_id>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify policy-id in the command. Use bizfly loadbalancer l7policy get <policy-id>")
+			os.Exit(1)
+		}
+		if len(args) > 1 {
+			fmt.Printf("Unknow variable %s", strings.Join(args[1:], ""))
+		}
+		client, ctx := getApiClient(cmd)
+		policy, err := client.CloudLoadBalancer.L7Policies().Get(ctx, args[0])
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("L7 policy %s not found.", args[0])
+				return
+			}
+			log.Fatal(err)
+		}
+		formatter.Output(l7PolicyListHeader, [][]string{l7PolicyRow(policy)})
+	},
+}
+
+// lbL7PolicyDeleteCmd represents the l7 policy delete command
+var lbL7PolicyDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete layer 7 policies",
+	Long: `Delete layer 7 policies with policy ID as input
+Example: bizfly loadbalancer l7policy delete <policy_id>
+
+You can delete multiple policies with list of policy ID
+Example: bizfly loadbalancer l7policy delete <policy_id_1> <policy_id_2>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, ctx := getApiClient(cmd)
+		for _, policyID := range args {
+			fmt.Printf("Deleting l7 policy %s \n", policyID)
+			err := client.CloudLoadBalancer.L7Policies().Delete(ctx, policyID)
+			if err != nil {
+				if errors.Is(err, gobizfly.ErrNotFound) {
+					fmt.Printf("L7 policy %s is not found\n", policyID)
+					continue
+				}
+				log.Fatal(err)
+			}
+		}
+	},
+}
+
+// lbL7RuleCreateCmd represents the l7 rule create command
+var lbL7RuleCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a layer 7 rule in a policy",
+	Long: `Create a layer 7 rule in a policy with policy ID as input.
+Type is one of PATH, HOST_NAME, HEADER, COOKIE or FILE_TYPE. HEADER and COOKIE rules need a key.
+Compare type is one of REGEX, STARTS_WITH, ENDS_WITH, CONTAINS or EQUAL_TO.
+Example: bizfly loadbalancer l7rule create <policy_id> --type PATH --compare-type STARTS_WITH --value /static
+Example: bizfly loadbalancer l7rule create <policy_id> --type HEADER --compare-type EQUAL_TO --key X-Version --value v2
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify policy-id in the command. Use bizfly loadbalancer l7rule create <policy-id>")
+			os.Exit(1)
+		}
+		if len(args) > 1 {
+			fmt.Printf("Unknow variable %s", strings.Join(args[1:], ""))
+		}
+		rule := gobizfly.L7PolicyRuleRequest{
+			Type:        strings.ToUpper(l7RuleType),
+			CompareType: strings.ToUpper(l7RuleCompareType),
+			Key:         l7RuleKey,
+			Value:       l7RuleValue,
+			Invert:      l7RuleInvert,
+		}
+		validateL7Rule(rule)
+		client, ctx := getApiClient(cmd)
+		created, err := client.CloudLoadBalancer.L7Policies().CreateL7PolicyRule(ctx, args[0], rule)
+		if err != nil {
+			log.Fatal(err)
+		}
+		formatter.Output(l7RuleListHeader, [][]string{l7RuleRow(*created)})
+	},
+}
+
+// lbL7RuleListCmd represents the l7 rule list command
+var lbL7RuleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all layer 7 rules in a policy",
+	Long: `List all layer 7 rules in a policy
+Example: bizfly loadbalancer l7rule list <policy_id>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify policy-id in the command. Use bizfly loadbalancer l7rule list <policy-id>")
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		rules, err := client.CloudLoadBalancer.L7Policies().ListL7PolicyRules(ctx, args[0])
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("L7 policy %s not found.", args[0])
+				return
+			}
+			log.Fatal(err)
+		}
+		var data [][]string
+		for _, rule := range rules {
+			data = append(data, l7RuleRow(rule))
+		}
+		formatter.Output(l7RuleListHeader, data)
+	},
+}
+
+// lbL7RuleDeleteCmd represents the l7 rule delete command
+var lbL7RuleDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete layer 7 rules from a policy",
+	Long: `Delete layer 7 rules from a policy with policy ID and rule IDs as input.
+The policy is updated with the remaining rules.
+Example: bizfly loadbalancer l7rule delete <policy_id> <rule_id>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			fmt.Println("You need to specify policy-id and rule-id in the command. Use bizfly loadbalancer l7rule delete <policy-id> <rule-id>")
+			os.Exit(1)
+		}
+		policyID := args[0]
+		client, ctx := getApiClient(cmd)
+		policy, err := client.CloudLoadBalancer.L7Policies().Get(ctx, policyID)
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("L7 policy %s not found.", policyID)
+				return
+			}
+			log.Fatal(err)
+		}
+		rules, err := client.CloudLoadBalancer.L7Policies().ListL7PolicyRules(ctx, policyID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		remaining := make([]gobizfly.UpdateL7PolicyRuleRequest, 0)
+		for _, rule := range rules {
+			if _, ok := SliceContains(args[1:], rule.Id); ok {
+				fmt.Printf("Deleting l7 rule %s \n", rule.Id)
+				continue
+			}
+			key := ""
+			if rule.Key != nil {
+				key = *rule.Key
+			}
+			remaining = append(remaining, gobizfly.UpdateL7PolicyRuleRequest{
+				ID: rule.Id,
+				L7PolicyRuleRequest: gobizfly.L7PolicyRuleRequest{
+					Type:        rule.Type,
+					CompareType: rule.CompareType,
+					Key:         key,
+					Value:       rule.Value,
+					Invert:      rule.Invert,
+				},
+			})
+		}
+		if len(remaining) == len(rules) {
+			fmt.Printf("Rules %s are not found in l7 policy %s\n", strings.Join(args[1:], ", "), policyID)
+			return
+		}
+		_, err = client.CloudLoadBalancer.L7Policies().Update(ctx, policyID, &gobizfly.UpdateL7PolicyRequest{
+			Name:           policy.Name,
+			Description:    policy.Description,
+			Action:         policy.Action,
+			Position:       policy.Position,
+			RedirectPoolId: policy.RedirectPoolId,
+			RedirectPrefix: policy.RedirectPrefix,
+			RedirectUrl:    policy.RedirectUrl,
+			Rules:          remaining,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func l7PolicyRow(policy *gobizfly.DetailL7Policy) []string {
+	redirectPoolID, redirectURL := "", ""
+	if policy.RedirectPoolId != nil {
+		redirectPoolID = *policy.RedirectPoolId
+	}
+	if policy.RedirectUrl != nil {
+		redirectURL = *policy.RedirectUrl
+	}
+	return []string{policy.Id, policy.Name, policy.Action, strconv.Itoa(policy.Position), redirectPoolID, redirectURL,
+		policy.OperatingStatus}
+}
+
+func l7RuleRow(rule gobizfly.DetailL7PolicyRule) []string {
+	key := ""
+	if rule.Key != nil {
+		key = *rule.Key
+	}
+	return []string{rule.Id, rule.Type, rule.CompareType, key, rule.Value, strconv.FormatBool(rule.Invert),
+		rule.OperatingStatus}
+}
+
+func validateL7PolicyAction(action, redirectPoolID, redirectURL string) {
+	if _, ok := SliceContains(l7PolicyActions, action); !ok {
+		log.Fatalf("Invalid action %s. Action must be one of %s", action, strings.Join(l7PolicyActions, ", "))
+	}
+	switch action {
+	case "REDIRECT_TO_POOL":
+		if redirectPoolID == "" {
+			log.Fatal("Action REDIRECT_TO_POOL needs --redirect-pool-id")
+		}
+		if redirectURL != "" {
+			log.Fatal("Action REDIRECT_TO_POOL does not take --redirect-url")
+		}
+	case "REDIRECT_TO_URL":
+		if redirectURL == "" {
+			log.Fatal("Action REDIRECT_TO_URL needs --redirect-url")
+		}
+		if redirectPoolID != "" {
+			log.Fatal("Action REDIRECT_TO_URL does not take --redirect-pool-id")
+		}
+	case "REJECT":
+		if redirectPoolID != "" || redirectURL != "" {
+			log.Fatal("Action REJECT does not take --redirect-pool-id or --redirect-url")
+		}
+	}
+}
+
+func validateL7Rule(rule gobizfly.L7PolicyRuleRequest) {
+	if _, ok := SliceContains(l7RuleTypes, rule.Type); !ok {
+		log.Fatalf("Invalid rule type %s. Type must be one of %s", rule.Type, strings.Join(l7RuleTypes, ", "))
+	}
+	if _, ok := SliceContains(l7RuleCompareTypes, rule.CompareType); !ok {
+		log.Fatalf("Invalid compare type %s. Compare type must be one of %s", rule.CompareType,
+			strings.Join(l7RuleCompareTypes, ", "))
+	}
+	if (rule.Type == "HEADER" || rule.Type == "COOKIE") && rule.Key == "" {
+		log.Fatalf("Rule type %s needs a key", rule.Type)
+	}
+	if rule.Value == "" {
+		log.Fatal("Rule needs a value")
+	}
+}
+
+// parseL7Rule parses a rule in the type=<type>,compare-type=<compare-type>,value=<value>[,key=<key>][,invert=true] format
+func parseL7Rule(ruleStr string) gobizfly.L7PolicyRuleRequest {
+	var rule gobizfly.L7PolicyRuleRequest
+	for _, pair := range strings.Split(ruleStr, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			log.Fatalf("Invalid rule %q", ruleStr)
+		}
+		switch kv[0] {
+		case "type":
+			rule.Type = strings.ToUpper(kv[1])
+		case "compare-type":
+			rule.CompareType = strings.ToUpper(kv[1])
+		case "value":
+			rule.Value = kv[1]
+		case "key":
+			rule.Key = kv[1]
+		case "invert":
+			invert, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				log.Fatalf("Invalid invert value of rule %q", ruleStr)
+			}
+			rule.Invert = invert
+		default:
+			log.Fatalf("Unknown field %s of rule %q", kv[0], ruleStr)
+		}
+	}
+	validateL7Rule(rule)
+	return rule
+}
+
+func init() {
+	lbCmd.AddCommand(lbL7PolicyCmd)
+	lbL7PolicyCmd.AddCommand(lbL7PolicyListCmd)
+	lbL7PolicyCmd.AddCommand(lbL7PolicyGetCmd)
+	lbL7PolicyCmd.AddCommand(lbL7PolicyDeleteCmd)
+	lbL7PolicyCmd.AddCommand(lbL7PolicyCreateCmd)
+	lpcf := lbL7PolicyCreateCmd.PersistentFlags()
+	lpcf.StringVar(&l7PolicyName, "name", "", "Name of the l7 policy")
+	_ = cobra.MarkFlagRequired(lpcf, "name")
+	lpcf.StringVar(&description, "description", "", "Description of the l7 policy")
+	lpcf.StringVar(&l7PolicyAction, "action", "", "Action of the l7 policy (REDIRECT_TO_POOL, REDIRECT_TO_URL, REJECT)")
+	_ = cobra.MarkFlagRequired(lpcf, "action")
+	lpcf.IntVar(&l7PolicyPosition, "position", 1, "Position of the l7 policy in the listener, starting from 1")
+	lpcf.StringVar(&l7PolicyRedirectPoolID, "redirect-pool-id", "", "ID of the pool for the REDIRECT_TO_POOL action")
+	lpcf.StringVar(&l7PolicyRedirectURL, "redirect-url", "", "URL for the REDIRECT_TO_URL action")
+	lpcf.StringArrayVar(&l7PolicyRules, "rule", []string{}, "Rule of the l7 policy in the "+
+		"type=<type>,compare-type=<compare-type>,value=<value>[,key=<key>][,invert=true] format. Can be repeated")
+
+	lbCmd.AddCommand(lbL7RuleCmd)
+	lbL7RuleCmd.AddCommand(lbL7RuleListCmd)
+	lbL7RuleCmd.AddCommand(lbL7RuleDeleteCmd)
+	lbL7RuleCmd.AddCommand(lbL7RuleCreateCmd)
+	lrcf := lbL7RuleCreateCmd.PersistentFlags()
+	lrcf.StringVar(&l7RuleType, "type", "", "Type of the l7 rule (PATH, HOST_NAME, HEADER, COOKIE, FILE_TYPE)")
+	_ = cobra.MarkFlagRequired(lrcf, "type")
+	lrcf.StringVar(&l7RuleCompareType, "compare-type", "", "Compare type of the l7 rule (REGEX, STARTS_WITH, ENDS_WITH, CONTAINS, EQUAL_TO)")
+	_ = cobra.MarkFlagRequired(lrcf, "compare-type")
+	lrcf.StringVar(&l7RuleKey, "key", "", "Key of the l7 rule. Required for HEADER and COOKIE rules")
+	lrcf.StringVar(&l7RuleValue, "value", "", "Value to compare")
+	_ = cobra.MarkFlagRequired(lrcf, "value")
+	lrcf.BoolVar(&l7RuleInvert, "invert", false, "Invert the result of the l7 rule")
+}
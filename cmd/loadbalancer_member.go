@@ -0,0 +1,331 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// lbServiceName is the service name of the load balancer endpoints in the service catalog, the same name
+	// gobizfly uses for Members(). It is only used for the member requests gobizfly sends with omitempty fields.
+	lbServiceName   = "load_balancer"
+	minMemberWeight = 0
+	maxMemberWeight = 256
+)
+
+var (
+	memberListHeader = []string{"ID", "Name", "Address", "Protocol Port", "Weight", "Backup", "Operating Status"}
+	memberName       string
+	memberAddress    string
+	memberPort       int
+	memberWeight     int
+	memberBackup     bool
+	memberServerID   string
+)
+
+var lbMemberCmd = &cobra.Command{
+	Use:   "member",
+	Short: "Bizfly Cloud Load Balancer Pool Member Interaction",
+	Long:  "Bizfly Cloud Load Balancer Pool Member Action: Add, List, Update, Remove, Drain, Undrain",
+	Run:   func(cmd *cobra.Command, args []string) {},
+}
+
+// lbMemberListCmd represents the member list command
+var lbMemberListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all members in a pool",
+	Long: `List all members in a load balancer pool
+Example: bizfly loadbalancer member list <pool_id>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify pool-id in the command. Use bizfly loadbalancer member list <pool-id>")
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		members, err := client.CloudLoadBalancer.Members().List(ctx, args[0], &gobizfly.ListOptions{})
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Pool %s not found.", args[0])
+				return
+			}
+			log.Fatal(err)
+		}
+		var data [][]string
+		for _, member := range members {
+			data = append(data, memberRow(member))
+		}
+		formatter.Output(memberListHeader, data)
+	},
+}
+
+// lbMemberAddCmd represents the member add command
+var lbMemberAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a member to a pool",
+	Long: `Add a backend member to a load balancer pool. The member address is either given with --address
+or resolved from the LAN IP of the server given with --server.
+Example: bizfly loadbalancer member add <pool_id> --name web-1 --address 10.20.1.5 --port 8080
+Example: bizfly loadbalancer member add <pool_id> --name web-1 --server <server_id> --port 8080 --weight 5
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify pool-id in the command. Use bizfly loadbalancer member add <pool-id>")
+			os.Exit(1)
+		}
+		if len(args) > 1 {
+			fmt.Printf("Unknow variable %s", strings.Join(args[1:], ""))
+		}
+		if memberAddress == "" && memberServerID == "" {
+			fmt.Println("You need to specify --address or --server to add a member")
+			os.Exit(1)
+		}
+		if memberAddress != "" && memberServerID != "" {
+			fmt.Println("Only one of --address and --server can be specified")
+			os.Exit(1)
+		}
+		if !validMemberWeight(memberWeight) {
+			fmt.Printf("Invalid weight %d. The weight is between %d and %d\n", memberWeight, minMemberWeight, maxMemberWeight)
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		address := memberAddress
+		name := memberName
+		if memberServerID != "" {
+			server, lanIP, err := getServerLanIP(ctx, client, memberServerID)
+			if err != nil {
+				fmt.Printf("Resolve server address error: %v\n", err)
+				os.Exit(1)
+			}
+			address = lanIP
+			if name == "" {
+				name = server.Name
+			}
+		}
+		member, err := createMember(ctx, client, args[0], &memberCreateRequest{
+			Name:         name,
+			Weight:       memberWeight,
+			Address:      address,
+			ProtocolPort: memberPort,
+			Backup:       memberBackup,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		formatter.Output(memberListHeader, [][]string{memberRow(member)})
+	},
+}
+
+// lbMemberUpdateCmd represents the member update command
+var lbMemberUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update a member of a pool",
+	Long: `Update a member of a load balancer pool. Only the given flags are changed.
+Example: bizfly loadbalancer member update <pool_id> <member_id> --weight 10
+Example: bizfly loadbalancer member update <pool_id> <member_id> --backup=false
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			fmt.Println("You need to specify pool-id and member-id in the command. Use bizfly loadbalancer member update <pool-id> <member-id>")
+			os.Exit(1)
+		}
+		poolID, memberID := args[0], args[1]
+		if cmd.Flags().Changed("weight") && !validMemberWeight(memberWeight) {
+			fmt.Printf("Invalid weight %d. The weight is between %d and %d\n", memberWeight, minMemberWeight, maxMemberWeight)
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		current, err := client.CloudLoadBalancer.Members().Get(ctx, poolID, memberID)
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Member %s not found in pool %s.", memberID, poolID)
+				return
+			}
+			log.Fatal(err)
+		}
+		payload := &memberUpdateRequest{
+			Name:   current.Name,
+			Weight: current.Weight,
+			Backup: current.Backup,
+		}
+		flags := cmd.Flags()
+		if flags.Changed("name") {
+			payload.Name = memberName
+		}
+		if flags.Changed("weight") {
+			payload.Weight = memberWeight
+		}
+		if flags.Changed("backup") {
+			payload.Backup = memberBackup
+		}
+		member, err := updateMember(ctx, client, poolID, memberID, payload)
+		if err != nil {
+			log.Fatal(err)
+		}
+		formatter.Output(memberListHeader, [][]string{memberRow(member)})
+	},
+}
+
+// lbMemberRemoveCmd represents the member remove command
+var lbMemberRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove members from a pool",
+	Long: `Remove members from a load balancer pool with pool ID and member IDs as input
+Example: bizfly loadbalancer member remove <pool_id> <member_id>
+
+You can remove multiple members with list of member ID
+Example: bizfly loadbalancer member remove <pool_id> <member_id_1> <member_id_2>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			fmt.Println("You need to specify pool-id and member-id in the command. Use bizfly loadbalancer member remove <pool-id> <member-id>")
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		poolID := args[0]
+		for _, memberID := range args[1:] {
+			fmt.Printf("Removing member %s \n", memberID)
+			err := client.CloudLoadBalancer.Members().Delete(ctx, poolID, memberID)
+			if err != nil {
+				if errors.Is(err, gobizfly.ErrNotFound) {
+					fmt.Printf("Member %s is not found\n", memberID)
+					continue
+				}
+				log.Fatal(err)
+			}
+		}
+	},
+}
+
+// memberCreateRequest represents create member request payload.
+// The weight and backup are always sent, unlike gobizfly.MemberCreateRequest which drops a zero weight and backup false.
+type memberCreateRequest struct {
+	Name         string `json:"name"`
+	Weight       int    `json:"weight"`
+	Address      string `json:"address"`
+	ProtocolPort int    `json:"protocol_port"`
+	Backup       bool   `json:"backup"`
+}
+
+// memberUpdateRequest represents update member request payload.
+// The weight and backup are always sent, unlike gobizfly.MemberUpdateRequest which drops a zero weight and backup false.
+type memberUpdateRequest struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+	Backup bool   `json:"backup"`
+}
+
+func validMemberWeight(weight int) bool {
+	return weight >= minMemberWeight && weight <= maxMemberWeight
+}
+
+// createMember creates a member with the same endpoint as gobizfly Members().Create
+func createMember(ctx context.Context, client *gobizfly.Client, poolID string, payload *memberCreateRequest) (*gobizfly.Member, error) {
+	var data struct {
+		Member *memberCreateRequest `json:"member"`
+	}
+	data.Member = payload
+	req, err := client.NewRequest(ctx, http.MethodPost, lbServiceName,
+		strings.Join([]string{"/pool", poolID, "member"}, "/"), &data)
+	if err != nil {
+		return nil, err
+	}
+	return doMemberRequest(ctx, client, req)
+}
+
+// updateMember updates a member with the same endpoint as gobizfly Members().Update
+func updateMember(ctx context.Context, client *gobizfly.Client, poolID, memberID string, payload *memberUpdateRequest) (*gobizfly.Member, error) {
+	var data struct {
+		Member *memberUpdateRequest `json:"member"`
+	}
+	data.Member = payload
+	req, err := client.NewRequest(ctx, http.MethodPut, lbServiceName,
+		strings.Join([]string{"/pool", poolID, "member", memberID}, "/"), &data)
+	if err != nil {
+		return nil, err
+	}
+	return doMemberRequest(ctx, client, req)
+}
+
+func doMemberRequest(ctx context.Context, client *gobizfly.Client, req *http.Request) (*gobizfly.Member, error) {
+	resp, err := client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var respData struct {
+		Member *gobizfly.Member `json:"member"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return nil, err
+	}
+	return respData.Member, nil
+}
+
+func memberRow(member *gobizfly.Member) []string {
+	return []string{member.ID, member.Name, member.Address, strconv.Itoa(member.ProtocolPort),
+		strconv.Itoa(member.Weight), strconv.FormatBool(member.Backup), member.OperatingStatus}
+}
+
+// getServerLanIP returns the server and its first LAN IPv4 address
+func getServerLanIP(ctx context.Context, client *gobizfly.Client, serverID string) (*gobizfly.Server, string, error) {
+	server, err := client.CloudServer.Get(ctx, serverID)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, lan := range server.IPAddresses.LanAddresses {
+		if lan.Version != 6 {
+			return server, lan.Address, nil
+		}
+	}
+	return nil, "", fmt.Errorf("server %s has no LAN IPv4 address", serverID)
+}
+
+func init() {
+	lbCmd.AddCommand(lbMemberCmd)
+	lbMemberCmd.AddCommand(lbMemberListCmd)
+
+	lbMemberCmd.AddCommand(lbMemberAddCmd)
+	mapf := lbMemberAddCmd.PersistentFlags()
+	mapf.StringVar(&memberName, "name", "", "Name of the member. Default is the server name when --server is used")
+	mapf.StringVar(&memberAddress, "address", "", "IP address of the member")
+	mapf.StringVar(&memberServerID, "server", "", "ID of the server. The member address is the LAN IP of the server")
+	mapf.IntVar(&memberPort, "port", 0, "Protocol port of the member")
+	_ = cobra.MarkFlagRequired(mapf, "port")
+	mapf.IntVar(&memberWeight, "weight", 1, "Weight of the member (0-256)")
+	mapf.BoolVar(&memberBackup, "backup", false, "Use the member as a backup member")
+
+	lbMemberCmd.AddCommand(lbMemberUpdateCmd)
+	mupf := lbMemberUpdateCmd.PersistentFlags()
+	mupf.StringVar(&memberName, "name", "", "Name of the member")
+	mupf.IntVar(&memberWeight, "weight", 1, "Weight of the member (0-256)")
+	mupf.BoolVar(&memberBackup, "backup", false, "Use the member as a backup member")
+
+	lbMemberCmd.AddCommand(lbMemberRemoveCmd)
+}
@@ -0,0 +1,206 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+	"github.com/spf13/cobra"
+)
+
+const (
+	memberStatusOnline    = "ONLINE"
+	memberStatusNoMonitor = "NO_MONITOR"
+)
+
+var (
+	switchToPoolID      string
+	switchMonitorPeriod time.Duration
+	switchCheckInterval time.Duration
+	switchRetries       int
+)
+
+// lbSwitchCmd represents the blue/green switch command of a listener
+var lbSwitchCmd = &cobra.Command{
+	Use:   "switch",
+	Short: "Switch the default pool of a listener when the new pool is healthy",
+	Long: `Switch the default pool of a listener to another pool for blue/green deploys.
+Every member of the target pool must be ONLINE before the switch. After the switch the target pool
+is monitored for the given period and the listener is switched back to the previous default pool
+if any member goes unhealthy. The target pool needs a health monitor, members of a pool without
+a health monitor report NO_MONITOR and their health can not be checked.
+Example: bizfly loadbalancer switch <listener_id> --to-pool <pool_id>
+Example: bizfly loadbalancer switch <listener_id> --to-pool <pool_id> --monitor 5m --interval 15s
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify listener-id in the command. Use bizfly loadbalancer switch <listener-id> --to-pool <pool-id>")
+			os.Exit(1)
+		}
+		if len(args) > 1 {
+			fmt.Printf("Unknow variable %s", strings.Join(args[1:], ""))
+		}
+		if switchCheckInterval <= 0 {
+			fmt.Println("--interval must be greater than 0")
+			os.Exit(1)
+		}
+		if switchRetries < 0 {
+			fmt.Println("--retries must not be negative")
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		listener, err := client.CloudLoadBalancer.Listeners().Get(ctx, args[0])
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Listener %s not found.", args[0])
+				return
+			}
+			log.Fatal(err)
+		}
+		previousPoolID := listener.DefaultPoolID
+		if previousPoolID == switchToPoolID {
+			fmt.Printf("Pool %s is already the default pool of listener %s\n", switchToPoolID, listener.ID)
+			return
+		}
+
+		pool, err := client.CloudLoadBalancer.Pools().Get(ctx, switchToPoolID)
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Pool %s not found.\n", switchToPoolID)
+				os.Exit(1)
+			}
+			log.Fatal(err)
+		}
+		if pool.HealthMonitorID == "" {
+			fmt.Printf("Pool %s has no health monitor, the health of its members can not be checked. "+
+				"Create one with bizfly loadbalancer health-monitor create\n", switchToPoolID)
+			os.Exit(1)
+		}
+		unhealthy, err := unhealthyPoolMembers(ctx, client, switchToPoolID)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if len(unhealthy) > 0 {
+			fmt.Printf("Pool %s is not healthy, listener %s is not switched:\n", switchToPoolID, listener.ID)
+			printMembers(unhealthy)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Switching default pool of listener %s from %s to %s\n", listener.ID, previousPoolID, switchToPoolID)
+		listener, err = setListenerDefaultPool(ctx, client, listener.ID, switchToPoolID)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if switchMonitorPeriod > 0 {
+			fmt.Printf("Monitoring pool %s for %s\n", switchToPoolID, switchMonitorPeriod)
+		}
+		deadline := time.Now().Add(switchMonitorPeriod)
+		failedChecks := 0
+		for time.Now().Before(deadline) {
+			time.Sleep(switchCheckInterval)
+			unhealthy, err = unhealthyPoolMembers(ctx, client, switchToPoolID)
+			if err == nil && len(unhealthy) == 0 {
+				failedChecks = 0
+				continue
+			}
+			if err != nil {
+				failedChecks++
+				fmt.Printf("Checking pool %s error: %v\n", switchToPoolID, err)
+				if failedChecks <= switchRetries {
+					continue
+				}
+			} else {
+				fmt.Printf("Pool %s became unhealthy:\n", switchToPoolID)
+				printMembers(unhealthy)
+			}
+			if previousPoolID == "" {
+				fmt.Printf("Listener %s had no default pool before, it is not rolled back\n", listener.ID)
+				os.Exit(1)
+			}
+			fmt.Printf("Rolling back default pool of listener %s to %s\n", listener.ID, previousPoolID)
+			if _, err := setListenerDefaultPool(ctx, client, listener.ID, previousPoolID); err != nil {
+				log.Fatalf("Rollback listener %s error: %v", listener.ID, err)
+			}
+			os.Exit(1)
+		}
+		var data [][]string
+		data = append(data, []string{listener.ID, listener.Name, listener.Protocol, strconv.Itoa(listener.ProtocolPort), listener.OperatingStatus, listener.DefaultPoolID})
+		formatter.Output(listenerListHeader, data)
+	},
+}
+
+// unhealthyPoolMembers returns the members of a pool which are not ONLINE
+func unhealthyPoolMembers(ctx context.Context, client *gobizfly.Client, poolID string) ([]*gobizfly.Member, error) {
+	members, err := client.CloudLoadBalancer.Members().List(ctx, poolID, &gobizfly.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return unhealthyMembers(poolID, members)
+}
+
+// unhealthyMembers returns the members which are not ONLINE. A pool without members is unhealthy and
+// a member with NO_MONITOR status is an error because its health is unknown.
+func unhealthyMembers(poolID string, members []*gobizfly.Member) ([]*gobizfly.Member, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("pool %s has no members", poolID)
+	}
+	var unhealthy []*gobizfly.Member
+	for _, member := range members {
+		switch member.OperatingStatus {
+		case memberStatusOnline:
+		case memberStatusNoMonitor:
+			return nil, fmt.Errorf("member %s of pool %s is not monitored, the pool needs a health monitor", member.ID, poolID)
+		default:
+			unhealthy = append(unhealthy, member)
+		}
+	}
+	return unhealthy, nil
+}
+
+func setListenerDefaultPool(ctx context.Context, client *gobizfly.Client, listenerID, poolID string) (*gobizfly.Listener, error) {
+	return client.CloudLoadBalancer.Listeners().Update(ctx, listenerID, &gobizfly.ListenerUpdateRequest{
+		DefaultPoolID: &poolID,
+	})
+}
+
+func printMembers(members []*gobizfly.Member) {
+	var data [][]string
+	for _, member := range members {
+		data = append(data, memberRow(member))
+	}
+	formatter.Output(memberListHeader, data)
+}
+
+func init() {
+	lbCmd.AddCommand(lbSwitchCmd)
+	lspf := lbSwitchCmd.PersistentFlags()
+	lspf.StringVar(&switchToPoolID, "to-pool", "", "ID of the pool to switch the listener to")
+	_ = cobra.MarkFlagRequired(lspf, "to-pool")
+	lspf.DurationVar(&switchMonitorPeriod, "monitor", 2*time.Minute, "Period to monitor the new pool after the switch. 0 disables monitoring")
+	lspf.DurationVar(&switchCheckInterval, "interval", 10*time.Second, "Interval between health checks while monitoring")
+	lspf.IntVar(&switchRetries, "retries", 3, "Number of consecutive failed health checks retried before rolling back")
+}
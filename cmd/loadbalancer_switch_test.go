@@ -0,0 +1,67 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"testing"
+
+	"github.com/bizflycloud/gobizfly"
+)
+
+func TestUnhealthyMembers(t *testing.T) {
+	member := func(id, status string) *gobizfly.Member {
+		return &gobizfly.Member{ID: id, OperatingStatus: status}
+	}
+	tests := []struct {
+		name      string
+		members   []*gobizfly.Member
+		unhealthy []string
+		wantErr   bool
+	}{
+		{name: "all online", members: []*gobizfly.Member{member("a", "ONLINE"), member("b", "ONLINE")}},
+		{name: "one error", members: []*gobizfly.Member{member("a", "ONLINE"), member("b", "ERROR")}, unhealthy: []string{"b"}},
+		{name: "offline and draining", members: []*gobizfly.Member{member("a", "OFFLINE"), member("b", "DRAINING")},
+			unhealthy: []string{"a", "b"}},
+		{name: "no monitor", members: []*gobizfly.Member{member("a", "ONLINE"), member("b", "NO_MONITOR")}, wantErr: true},
+		{name: "no members", wantErr: true},
+	}
+	for _, tt := range tests {
+		unhealthy, err := unhealthyMembers("pool", tt.members)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		var ids []string
+		for _, m := range unhealthy {
+			ids = append(ids, m.ID)
+		}
+		if len(ids) != len(tt.unhealthy) {
+			t.Errorf("%s: unhealthy = %v, want %v", tt.name, ids, tt.unhealthy)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.unhealthy[i] {
+				t.Errorf("%s: unhealthy = %v, want %v", tt.name, ids, tt.unhealthy)
+			}
+		}
+	}
+}
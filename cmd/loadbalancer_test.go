@@ -0,0 +1,148 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"testing"
+)
+
+func TestParseLoadBalancerListener(t *testing.T) {
+	tests := []struct {
+		spec       string
+		hmType     string
+		wantErr    bool
+		protocol   string
+		port       int
+		pool       string
+		tlsRef     string
+		wantHMType string
+		urlPath    string
+	}{
+		{spec: "HTTP:80", protocol: "HTTP", port: 80, pool: "pool-80", wantHMType: "HTTP", urlPath: "/"},
+		{spec: "http:8080:web", protocol: "HTTP", port: 8080, pool: "web", wantHMType: "HTTP", urlPath: "/"},
+		{spec: "TERMINATED_HTTPS:443:web-tls:https://secret.example/v1/containers/1", protocol: "TERMINATED_HTTPS",
+			port: 443, pool: "web-tls", tlsRef: "https://secret.example/v1/containers/1", wantHMType: "HTTP", urlPath: "/"},
+		{spec: "TCP:8443:api", protocol: "TCP", port: 8443, pool: "api", wantHMType: "TCP"},
+		{spec: "UDP:53:dns", protocol: "UDP", port: 53, pool: "dns", wantHMType: "UDP-CONNECT"},
+		{spec: "TCP:8443", hmType: "HTTP", protocol: "TCP", port: 8443, pool: "pool-8443", wantHMType: "HTTP", urlPath: "/"},
+		{spec: "HTTP:80", hmType: "PING", protocol: "HTTP", port: 80, pool: "pool-80", wantHMType: "PING"},
+		{spec: "HTTP", wantErr: true},
+		{spec: ":80", wantErr: true},
+		{spec: "HTTP:http", wantErr: true},
+		{spec: "HTTP:0", wantErr: true},
+		{spec: "HTTP:65536", wantErr: true},
+	}
+	for _, tt := range tests {
+		listener, err := parseLoadBalancerListener(tt.spec, tt.hmType)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseLoadBalancerListener(%q) expected an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseLoadBalancerListener(%q) error: %v", tt.spec, err)
+			continue
+		}
+		hm := listener.DefaultPool.HealthMonitor
+		if listener.Protocol != tt.protocol || listener.ProtocolPort != tt.port || listener.DefaultPool.Name != tt.pool ||
+			listener.DefaultTLSRef != tt.tlsRef || hm.Type != tt.wantHMType || hm.URLPath != tt.urlPath {
+			t.Errorf("parseLoadBalancerListener(%q) = %s %d pool %s ref %s monitor %s %q", tt.spec, listener.Protocol,
+				listener.ProtocolPort, listener.DefaultPool.Name, listener.DefaultTLSRef, hm.Type, hm.URLPath)
+		}
+		if !isHTTPHealthMonitor(hm.Type) && (hm.HTTPMethod != "" || hm.ExpectedCodes != "") {
+			t.Errorf("parseLoadBalancerListener(%q) kept HTTP fields in %s health monitor", tt.spec, hm.Type)
+		}
+	}
+}
+
+func TestParseLoadBalancerMember(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    lbMemberSpec
+		wantErr bool
+	}{
+		{spec: "web=10.20.1.5:8080", want: lbMemberSpec{Pool: "web", Address: "10.20.1.5", Port: 8080}},
+		{spec: "web=[fd00::5]:80", want: lbMemberSpec{Pool: "web", Address: "fd00::5", Port: 80}},
+		{spec: "web=10.20.1.5", wantErr: true},
+		{spec: "web=host.local:80", wantErr: true},
+		{spec: "web=10.20.1.5:0", wantErr: true},
+		{spec: "web=10.20.1.5:http", wantErr: true},
+		{spec: "=10.20.1.5:80", wantErr: true},
+		{spec: "web=", wantErr: true},
+		{spec: "10.20.1.5:80", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseLoadBalancerMember(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseLoadBalancerMember(%q) expected an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseLoadBalancerMember(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidateExpectedCodes(t *testing.T) {
+	tests := []struct {
+		codes   string
+		wantErr bool
+	}{
+		{codes: "200"},
+		{codes: "200,202,204"},
+		{codes: "200-204"},
+		{codes: "20", wantErr: true},
+		{codes: "2000", wantErr: true},
+		{codes: "099", wantErr: true},
+		{codes: "600", wantErr: true},
+		{codes: "204-200", wantErr: true},
+		{codes: "200-202-204", wantErr: true},
+		{codes: "200,", wantErr: true},
+		{codes: "ok", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := validateExpectedCodes(tt.codes); (err != nil) != tt.wantErr {
+			t.Errorf("validateExpectedCodes(%q) error = %v, wantErr %v", tt.codes, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateHealthMonitor(t *testing.T) {
+	tests := []struct {
+		name    string
+		hm      healthMonitorSettings
+		wantErr bool
+	}{
+		{name: "http defaults", hm: healthMonitorSettings{Type: "HTTP", URLPath: "/"}},
+		{name: "domain with 1.1", hm: healthMonitorSettings{Type: "HTTP", DomainName: "example.com", HTTPVersion: 1.1}},
+		{name: "domain with 1.0", hm: healthMonitorSettings{Type: "HTTP", DomainName: "example.com", HTTPVersion: 1.0},
+			wantErr: true},
+		{name: "domain without version", hm: healthMonitorSettings{Type: "HTTPS", DomainName: "example.com"},
+			wantErr: true},
+		{name: "invalid version", hm: healthMonitorSettings{Type: "HTTP", HTTPVersion: 2}, wantErr: true},
+		{name: "invalid codes", hm: healthMonitorSettings{Type: "HTTP", ExpectedCodes: "2xx"}, wantErr: true},
+		{name: "relative path", hm: healthMonitorSettings{Type: "HTTP", URLPath: "health"}, wantErr: true},
+		{name: "tcp ignores http fields", hm: healthMonitorSettings{Type: "TCP", DomainName: "example.com"}},
+	}
+	for _, tt := range tests {
+		if err := validateHealthMonitor(tt.hm); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateHealthMonitor error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
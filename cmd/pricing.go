@@ -0,0 +1,324 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	pricingFileName      = ".bizfly_pricing.yml"
+	defaultHoursPerMonth = 730
+	pricingFetchTimeout  = 30 * time.Second
+)
+
+var (
+	estimateCost   bool
+	pricingFile    string
+	pricingURL     string
+	pricingClient  = &http.Client{Timeout: pricingFetchTimeout}
+	estimateHeader = []string{"Item", "Quantity", "On Demand Hourly", "On Demand Monthly", "Saving Plan Hourly",
+		"Saving Plan Monthly"}
+)
+
+// price is the hourly price of one unit for each billing plan
+type price struct {
+	OnDemand   float64 `yaml:"on_demand"`
+	SavingPlan float64 `yaml:"saving_plan"`
+}
+
+// serverPricing is the hourly price of a vCPU and of a GB of RAM
+type serverPricing struct {
+	VCPU  price `yaml:"vcpu"`
+	RAMGB price `yaml:"ram_gb"`
+}
+
+// pricing is the pricing table. Server prices are keyed by category, volume prices per GB are keyed by volume type
+// and load balancer prices are keyed by load balancer type.
+type pricing struct {
+	Currency      string                   `yaml:"currency"`
+	HoursPerMonth float64                  `yaml:"hours_per_month"`
+	Server        map[string]serverPricing `yaml:"server"`
+	Volume        map[string]price         `yaml:"volume"`
+	LoadBalancer  map[string]price         `yaml:"loadbalancer"`
+	WanIP         price                    `yaml:"wan_ip"`
+}
+
+// costLine is an item of an estimate: the quantity of units and the price of a unit
+type costLine struct {
+	Item     string
+	Quantity float64
+	Price    price
+}
+
+// loadPricing loads the pricing table from --pricing-url, the pricing_url config or --pricing-file
+func loadPricing() *pricing {
+	p, err := readPricing()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return p
+}
+
+// readPricing reads the pricing table like loadPricing and returns the error instead of exiting
+func readPricing() (*pricing, error) {
+	url := pricingURL
+	if url == "" {
+		url = viper.GetString("pricing_url")
+	}
+	var data []byte
+	var source string
+	if url != "" {
+		resp, err := pricingClient.Get(url)
+		if err != nil {
+			return nil, fmt.Errorf("fetch pricing from %s error: %v", url, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch pricing from %s error: %s", url, resp.Status)
+		}
+		if data, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, fmt.Errorf("fetch pricing from %s error: %v", url, err)
+		}
+		source = url
+	} else {
+		path := pricingFile
+		if path == "" {
+			home, err := homedir.Dir()
+			if err != nil {
+				return nil, err
+			}
+			path = filepath.Join(home, pricingFileName)
+		}
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("read pricing file error: %v. Use --pricing-file or --pricing-url, see config_file_examples/pricing/pricing.yml", err)
+		}
+		source = path
+	}
+	var p pricing
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid pricing %s: %v", source, err)
+	}
+	if p.HoursPerMonth == 0 {
+		p.HoursPerMonth = defaultHoursPerMonth
+	}
+	return &p, nil
+}
+
+func (p *pricing) serverLines(category string, vcpus, ramMB int, count int) ([]costLine, error) {
+	sp, ok := p.Server[category]
+	if !ok {
+		return nil, fmt.Errorf("pricing has no server price for category %s", category)
+	}
+	return []costLine{
+		{Item: fmt.Sprintf("vCPU (%s)", category), Quantity: float64(vcpus * count), Price: sp.VCPU},
+		{Item: fmt.Sprintf("RAM GB (%s)", category), Quantity: float64(ramMB*count) / 1024, Price: sp.RAMGB},
+	}, nil
+}
+
+// volumeLine returns the cost of a volume. The price is looked up by volume type, then by HDD or SSD
+// when the volume type contains it, e.g. PREMIUM-SSD1 falls back to SSD.
+func (p *pricing) volumeLine(item, volumeType string, sizeGB int) (costLine, error) {
+	vp, ok := p.Volume[volumeType]
+	if !ok {
+		vp, ok = p.Volume[strings.ToUpper(volumeType)]
+	}
+	for _, diskType := range rootDiskTypes {
+		if !ok && strings.Contains(strings.ToUpper(volumeType), diskType) {
+			vp, ok = p.Volume[diskType]
+		}
+	}
+	if !ok {
+		return costLine{}, fmt.Errorf("pricing has no volume price for type %s", volumeType)
+	}
+	return costLine{Item: fmt.Sprintf("%s GB (%s)", item, volumeType), Quantity: float64(sizeGB), Price: vp}, nil
+}
+
+func (p *pricing) loadBalancerLine(lbType string) (costLine, error) {
+	lp, ok := p.LoadBalancer[lbType]
+	if !ok {
+		return costLine{}, fmt.Errorf("pricing has no load balancer price for type %s", lbType)
+	}
+	return costLine{Item: fmt.Sprintf("Load balancer (%s)", lbType), Quantity: 1, Price: lp}, nil
+}
+
+func (p *pricing) wanIPLine(count int) costLine {
+	return costLine{Item: "WAN IP", Quantity: float64(count), Price: p.WanIP}
+}
+
+// printEstimate prints the hourly and monthly cost of each line and the total for both billing plans
+func (p *pricing) printEstimate(lines []costLine) {
+	var data [][]string
+	var total price
+	for _, line := range lines {
+		cost := price{OnDemand: line.Quantity * line.Price.OnDemand, SavingPlan: line.Quantity * line.Price.SavingPlan}
+		total.OnDemand += cost.OnDemand
+		total.SavingPlan += cost.SavingPlan
+		data = append(data, append([]string{line.Item, formatQuantity(line.Quantity)}, p.costColumns(cost)...))
+	}
+	data = append(data, append([]string{"Total", ""}, p.costColumns(total)...))
+	formatter.Output(estimateHeader, data)
+	fmt.Printf("Prices in %s, %v hours per month. Nothing is created or changed.\n", p.Currency, p.HoursPerMonth)
+}
+
+// printDifference prints the cost before and after a change and the difference
+func (p *pricing) printDifference(before, after []costLine) {
+	sum := func(lines []costLine) price {
+		var total price
+		for _, line := range lines {
+			total.OnDemand += line.Quantity * line.Price.OnDemand
+			total.SavingPlan += line.Quantity * line.Price.SavingPlan
+		}
+		return total
+	}
+	b, a := sum(before), sum(after)
+	diff := price{OnDemand: a.OnDemand - b.OnDemand, SavingPlan: a.SavingPlan - b.SavingPlan}
+	data := [][]string{
+		append([]string{"Current", ""}, p.costColumns(b)...),
+		append([]string{"New", ""}, p.costColumns(a)...),
+		append([]string{"Difference", ""}, p.costColumns(diff)...),
+	}
+	formatter.Output(estimateHeader, data)
+	fmt.Printf("Prices in %s, %v hours per month. Nothing is created or changed.\n", p.Currency, p.HoursPerMonth)
+}
+
+func (p *pricing) costColumns(hourly price) []string {
+	return []string{
+		formatPrice(hourly.OnDemand), formatPrice(hourly.OnDemand * p.HoursPerMonth),
+		formatPrice(hourly.SavingPlan), formatPrice(hourly.SavingPlan * p.HoursPerMonth),
+	}
+}
+
+func formatPrice(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func formatQuantity(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// serverCreateCostLines returns the cost of the servers, root disks, data disks and WAN IPs of a create request
+func serverCreateCostLines(ctx context.Context, client *gobizfly.Client, p *pricing, scr *gobizfly.ServerCreateRequest,
+	count int) ([]costLine, error) {
+	flavor, err := findFlavor(ctx, client, scr.FlavorName, scr.Type)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := p.serverLines(scr.Type, flavor.VCPUs, flavor.RAM, count)
+	if err != nil {
+		return nil, err
+	}
+	disks := append([]*gobizfly.ServerDisk{scr.RootDisk}, scr.DataDisks...)
+	for i, disk := range disks {
+		item := "Root disk"
+		if i > 0 {
+			item = fmt.Sprintf("Data disk %d", i)
+		}
+		line, err := p.volumeLine(item, serverDiskType(disk), disk.Size*count)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	if scr.IsCreatedWan == nil || *scr.IsCreatedWan {
+		lines = append(lines, p.wanIPLine(count))
+	}
+	return lines, nil
+}
+
+// serverResizeCostLines returns the cost of the server with the current flavor and with the new flavor.
+// The new flavor is in the new category, or in the category of the server when the new category is empty.
+func serverResizeCostLines(ctx context.Context, client *gobizfly.Client, p *pricing, serverID, newFlavor,
+	newCategory string) ([]costLine, []costLine, error) {
+	server, err := client.CloudServer.Get(ctx, serverID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if newCategory == "" {
+		newCategory = server.Category
+	}
+	current, err := findFlavor(ctx, client, server.FlavorName, server.Category)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := findFlavor(ctx, client, newFlavor, newCategory)
+	if err != nil {
+		return nil, nil, err
+	}
+	before, err := p.serverLines(server.Category, current.VCPUs, current.RAM, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := p.serverLines(newCategory, target.VCPUs, target.RAM, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// findFlavor returns the flavor of the category by its full name or its short name
+func findFlavor(ctx context.Context, client *gobizfly.Client, name, category string) (*flavorInfo, error) {
+	flavors, err := listFlavors(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	for i, flavor := range flavors {
+		if flavor.Category == category && sameFlavor(flavor.Name, name) {
+			return &flavors[i], nil
+		}
+	}
+	return nil, fmt.Errorf("flavor %s not found in category %s", name, category)
+}
+
+func serverDiskType(disk *gobizfly.ServerDisk) string {
+	if disk.VolumeType != nil && *disk.VolumeType != "" {
+		return *disk.VolumeType
+	}
+	if disk.Type != nil {
+		return *disk.Type
+	}
+	return "HDD"
+}
+
+// addEstimateFlags adds the --estimate, --pricing-file and --pricing-url flags to a command
+func addEstimateFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&estimateCost, "estimate", false, "Print the hourly and monthly cost for on_demand and saving_plan without submitting")
+	addPricingFlags(cmd)
+}
+
+// addPricingFlags adds the --pricing-file and --pricing-url flags to a command
+func addPricingFlags(cmd *cobra.Command) {
+	pf := cmd.PersistentFlags()
+	pf.StringVar(&pricingFile, "pricing-file", "", "Path of the pricing file. Default is ~/"+pricingFileName)
+	pf.StringVar(&pricingURL, "pricing-url", "", "URL of the pricing table. Default is pricing_url in the config file")
+}
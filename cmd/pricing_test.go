@@ -0,0 +1,51 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import "testing"
+
+func TestVolumeLine(t *testing.T) {
+	p := &pricing{Volume: map[string]price{
+		"HDD":          {OnDemand: 2, SavingPlan: 1.6},
+		"SSD":          {OnDemand: 5, SavingPlan: 4},
+		"PREMIUM-SSD1": {OnDemand: 6, SavingPlan: 5},
+	}}
+	tests := []struct {
+		volumeType string
+		want       price
+		wantErr    bool
+	}{
+		{volumeType: "PREMIUM-SSD1", want: price{OnDemand: 6, SavingPlan: 5}},
+		{volumeType: "hdd", want: price{OnDemand: 2, SavingPlan: 1.6}},
+		{volumeType: "BASIC_HDD1", want: price{OnDemand: 2, SavingPlan: 1.6}},
+		{volumeType: "ENTERPRISE-SSD1", want: price{OnDemand: 5, SavingPlan: 4}},
+		{volumeType: "premium-ssd2", want: price{OnDemand: 5, SavingPlan: 4}},
+		{volumeType: "NVME1", wantErr: true},
+		{volumeType: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		line, err := p.volumeLine("Volume", tt.volumeType, 10)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("volumeLine(%q) expected an error", tt.volumeType)
+			}
+			continue
+		}
+		if err != nil || line.Price != tt.want || line.Quantity != 10 {
+			t.Errorf("volumeLine(%q) = %+v, %v, want price %+v", tt.volumeType, line, err, tt.want)
+		}
+	}
+}
@@ -16,16 +16,23 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/bizflycloud/bizflyctl/formatter"
 	"github.com/bizflycloud/gobizfly"
 	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
 )
 
 var (
@@ -49,9 +56,11 @@ var (
 	rootDiskType       string
 	rootDiskVolumeType string
 	rootDiskSize       int
-	// ssh key
-	sshKey         string
+	// ssh keys, the first key is the key of the server and the others are added by cloud-init
+	sshKeys        []string
 	deleteRootDisk bool
+	userDataFile   string
+	dataDisks      []string
 	// vpc ids
 	vpcIDs            []string
 	networkInterfaces []string
@@ -59,9 +68,28 @@ var (
 	networkPlan       string
 	billingPlan       string
 	isCreatedWan      bool
+
+	// server list filters
+	serverFilterStatus   string
+	serverFilterZone     string
+	serverFilterFlavor   string
+	serverFilterName     string
+	serverFilterIP       string
+	serverFilterCategory string
+	serverSortBy         string
+	serverSortReverse    bool
+	serverListLimit      int
+	serverListPage       int
+
+	authorizedKeysLineRegexp = regexp.MustCompile(`^ssh_authorized_keys:\s*(#.*)?$`)
 )
 
-const attachTypeRootDisk = "rootdisk"
+const (
+	attachTypeRootDisk = "rootdisk"
+	cloudConfigHeader  = "#cloud-config"
+	// maxUserDataSize is the maximum size of base64 encoded user data
+	maxUserDataSize = 65535
+)
 
 //type responseMessage struct {
 //	message string `json:"message"`
@@ -128,13 +156,45 @@ Example: bizfly server delete fd554aac-9ab1-11ea-b09d-bbaf82f02f58 f5869e9c-9ab2
 var serverListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all server in your account",
-	Long:  `List all server in your account`,
+	Long: `List all server in your account.
+Name is an exact name (web-1), a glob pattern (web-*) or a regular expression between slashes (/^web-[0-9]+$/).
+Example: bizfly server list --status ACTIVE --zone HN1 --name 'web-*' --sort-by created --limit 20 --page 2
+`,
 	Run: func(cmd *cobra.Command, args []string) {
+		opts := &gobizfly.ServerListOptions{
+			Status: strings.ToUpper(serverFilterStatus),
+			IP:     serverFilterIP,
+		}
+		nameMatcher, err := newNameMatcher(serverFilterName)
+		if err != nil {
+			fmt.Printf("Invalid name filter %s: %v\n", serverFilterName, err)
+			os.Exit(1)
+		}
+		if nameMatcher.plain {
+			opts.Name = serverFilterName
+		}
+		if serverSortBy != "" && serverSortBy != "created" && serverSortBy != "name" && serverSortBy != "status" {
+			fmt.Printf("Invalid sort key %s. Use created, name or status\n", serverSortBy)
+			os.Exit(1)
+		}
+		if serverListLimit < 0 || serverListPage < 1 {
+			fmt.Println("--limit must not be negative and --page must be greater than 0")
+			os.Exit(1)
+		}
 		client, ctx := getApiClient(cmd)
-		servers, err := client.CloudServer.List(ctx, &gobizfly.ServerListOptions{})
+		servers, err := client.CloudServer.List(ctx, opts)
 		if err != nil {
 			log.Fatal(err)
 		}
+		servers = filterServers(servers, &serverFilter{
+			Status:   serverFilterStatus,
+			Zone:     serverFilterZone,
+			Flavor:   serverFilterFlavor,
+			Category: serverFilterCategory,
+			Names:    nameMatcher,
+		})
+		sortServers(servers, serverSortBy, serverSortReverse)
+		servers = paginateServers(servers, serverListLimit, serverListPage)
 		var data [][]string
 		for _, server := range servers {
 			var LanIP []string
@@ -152,7 +212,7 @@ var serverListCmd = &cobra.Command{
 			WanIPAddrs := strings.Join(WanIP, ", ")
 			data = append(data, []string{server.ID, server.Name, server.AvailabilityZone, server.KeyName, server.Status, server.FlavorName, server.Category, LanIPAddrs, WanIPAddrs, server.CreatedAt})
 		}
-		listServerListHeader := append(serverListHeader[:len(serverListHeader)-2], serverListHeader[len(serverListHeader)-1])
+		listServerListHeader := append(serverListHeader[:len(serverListHeader)-2:len(serverListHeader)-2], serverListHeader[len(serverListHeader)-1])
 		formatter.Output(listServerListHeader, data)
 	},
 }
@@ -206,62 +266,167 @@ Example: bizfly server get fd554aac-9ab1-11ea-b09d-bbaf82f02f58
 var serverCreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a server",
-	Long:  "Create a new server, return a task ID of the processing",
+	Long: `Create a new server, return a task ID of the processing.
+The flavor, image, volume types and availability zone are checked before the server is created, use --skip-preflight to skip the checks.
+Example: bizfly server create --name web-1 --flavor nix.2c_4g --image-id <image-id> --rootdisk-size 40 --ssh-key key1 --ssh-key key2 --user-data-file cloud-init.yaml --data-disk size=100,type=SSD --vpc-ids <vpc-id>
+Example: bizfly server create --name web-1 --flavor auto --vcpus 3 --ram 6G --image-id <image-id> --rootdisk-size 40
+Example: bizfly server create --interactive
+Example: bizfly server create --count 10 --name 'web-{{.Index}}' --flavor nix.2c_4g --image-id <image-id> --rootdisk-size 40 --wait`,
 	Run: func(cmd *cobra.Command, arg []string) {
+		if !serverCreateInteractive {
+			var missing []string
+			for _, flag := range []string{"name", "flavor", "rootdisk-size"} {
+				if !cmd.Flags().Changed(flag) {
+					missing = append(missing, fmt.Sprintf("%q", flag))
+				}
+			}
+			if len(missing) > 0 {
+				fmt.Printf("required flag(s) %s not set, or use --interactive\n", strings.Join(missing, ", "))
+				os.Exit(1)
+			}
+		}
+		client, ctx := getApiClient(cmd)
+		if serverCreateInteractive && !runServerCreateWizard(ctx, client) {
+			fmt.Println("Server is not created")
+			return
+		}
 
 		if imageID == "" && volumeID == "" && snapshotID == "" {
 			fmt.Println("You need to specify image-id or volume-id or snapshot-id to create a new server")
+			os.Exit(1)
 		}
-
-		var serverOS gobizfly.ServerOS
-
-		if imageID != "" {
-			serverOS.Type = "image"
-			serverOS.ID = imageID
+		if flavorName == "auto" {
+			minRAM, err := parseRAM(suggestRAM)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			flavor, err := suggestFlavor(ctx, client, suggestVCPUs, minRAM, serverCategory, nil)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			flavorName = flavor.Name
+			fmt.Printf("Using flavor %s (%d vCPUs, %d GB RAM)\n", flavor.Name, flavor.VCPUs, flavor.RAM/1024)
 		}
-		if volumeID != "" {
-			serverOS.Type = "volume"
-			serverOS.ID = volumeID
+		if serverCount < 1 || serverParallel < 1 {
+			fmt.Println("--count and --parallel must be greater than 0")
+			os.Exit(1)
 		}
-
-		if snapshotID != "" {
-			serverOS.Type = "snapshot"
-			serverOS.ID = snapshotID
+		names, err := serverNames(serverName, serverCount)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
-		rootDisk := gobizfly.ServerDisk{
-			Size: rootDiskSize,
+		scr := buildServerCreateRequest(ctx, client)
+		if estimateCost {
+			p := loadPricing()
+			lines, err := serverCreateCostLines(ctx, client, p, scr, serverCount)
+			if err != nil {
+				fmt.Printf("Estimate cost error: %v\n", err)
+				os.Exit(1)
+			}
+			p.printEstimate(lines)
+			return
 		}
-		if rootDiskVolumeType != "" {
-			rootDisk.VolumeType = &rootDiskVolumeType
-		} else {
-			rootDisk.Type = &rootDiskType
+		if !skipPreflight {
+			problems, err := preflightServerCreate(ctx, client, scr)
+			if err != nil {
+				fmt.Printf("Preflight check error: %v\n", err)
+				os.Exit(1)
+			}
+			if len(problems) > 0 {
+				fmt.Println("Server is not created:")
+				for _, problem := range problems {
+					fmt.Printf("  - %s\n", problem)
+				}
+				os.Exit(1)
+			}
 		}
+		if serverCount == 1 && !serverCreateWait {
+			scr.Name = names[0]
+			svrTask, err := client.CloudServer.Create(ctx, scr)
+			if err != nil {
+				fmt.Printf("Create server error: %v", err)
+				os.Exit(1)
+			}
 
-		scr := gobizfly.ServerCreateRequest{
-			Name:              serverName,
-			FlavorName:        flavorName,
-			SSHKey:            sshKey,
-			RootDisk:          &rootDisk,
-			Type:              serverCategory,
-			AvailabilityZone:  availabilityZone,
-			OS:                &serverOS,
-			NetworkPlan:       networkPlan,
-			Firewalls:         firewalls,
-			NetworkInterfaces: networkInterfaces,
-			BillingPlan:       billingPlan,
-			IsCreatedWan:      &isCreatedWan,
+			fmt.Printf("Creating server with task id: %v\n", svrTask.Task[0])
+			return
 		}
-		client, ctx := getApiClient(cmd)
-		svrTask, err := client.CloudServer.Create(ctx, &scr)
-		if err != nil {
-			fmt.Printf("Create server error: %v", err)
+		results := createServers(ctx, client, scr, names)
+		if printServerCreateResults(results) > 0 {
 			os.Exit(1)
 		}
-
-		fmt.Printf("Creating server with task id: %v\n", svrTask.Task[0])
 	},
 }
 
+// buildServerCreateRequest builds the create server request from the flags
+func buildServerCreateRequest(ctx context.Context, client *gobizfly.Client) *gobizfly.ServerCreateRequest {
+	var serverOS gobizfly.ServerOS
+
+	if imageID != "" {
+		serverOS.Type = "image"
+		serverOS.ID = imageID
+	}
+	if volumeID != "" {
+		serverOS.Type = "volume"
+		serverOS.ID = volumeID
+	}
+
+	if snapshotID != "" {
+		serverOS.Type = "snapshot"
+		serverOS.ID = snapshotID
+	}
+	rootDisk := gobizfly.ServerDisk{
+		Size: rootDiskSize,
+	}
+	if rootDiskVolumeType != "" {
+		rootDisk.VolumeType = &rootDiskVolumeType
+	} else {
+		rootDisk.Type = &rootDiskType
+	}
+
+	scr := gobizfly.ServerCreateRequest{
+		Name:              serverName,
+		FlavorName:        flavorName,
+		RootDisk:          &rootDisk,
+		Type:              serverCategory,
+		AvailabilityZone:  availabilityZone,
+		OS:                &serverOS,
+		NetworkPlan:       networkPlan,
+		Firewalls:         firewalls,
+		NetworkInterfaces: networkInterfaces,
+		VPCNetworkIds:     vpcIDs,
+		BillingPlan:       billingPlan,
+		IsCreatedWan:      &isCreatedWan,
+	}
+	for _, diskStr := range dataDisks {
+		disk, err := parseDataDisk(diskStr)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		scr.DataDisks = append(scr.DataDisks, disk)
+	}
+	if len(sshKeys) > 0 {
+		scr.SSHKey = sshKeys[0]
+	}
+	var extraKeys []string
+	if len(sshKeys) > 1 {
+		extraKeys = getSSHPublicKeys(ctx, client, sshKeys[1:])
+	}
+	if userDataFile != "" || len(extraKeys) > 0 {
+		userData, err := buildUserData(userDataFile, extraKeys)
+		if err != nil {
+			fmt.Printf("Invalid user data: %v\n", err)
+			os.Exit(1)
+		}
+		scr.UserData = userData
+	}
+	return &scr
+}
+
 // serverRebootCmd represents the reboot server command
 var serverRebootCmd = &cobra.Command{
 	Use:   "reboot",
@@ -289,18 +454,18 @@ Use: bizfly server reboot <server-id>
 
 // serverHardRebootCmd represents the hard reboot server command
 var serverHardRebootCmd = &cobra.Command{
-	Use:   "hard reboot",
+	Use:   "hard-reboot",
 	Short: "Hard reboot a server",
 	Long: `
 Hard reboot a server.
-Use: bizfly server hard reboot <server-id>
+Use: bizfly server hard-reboot <server-id>
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) < 2 {
-			fmt.Println("You need to specify server-id in the command. Use bizfly server hard reboot <server-id>")
+		if len(args) < 1 {
+			fmt.Println("You need to specify server-id in the command. Use bizfly server hard-reboot <server-id>")
 			os.Exit(1)
 		}
-		serverID := args[1]
+		serverID := args[0]
 		client, ctx := getApiClient(cmd)
 		res, err := client.CloudServer.HardReboot(ctx, serverID)
 		if err != nil {
@@ -312,6 +477,21 @@ Use: bizfly server hard reboot <server-id>
 	},
 }
 
+// serverHardCmd keeps the deprecated "bizfly server hard reboot <server-id>" form working
+var serverHardCmd = &cobra.Command{
+	Use:        "hard",
+	Short:      "Hard reboot a server",
+	Hidden:     true,
+	Deprecated: "use bizfly server hard-reboot <server-id>",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 || args[0] != "reboot" {
+			fmt.Println("You need to specify server-id in the command. Use bizfly server hard-reboot <server-id>")
+			os.Exit(1)
+		}
+		serverHardRebootCmd.Run(cmd, args[1:])
+	},
+}
+
 // serverStopCmd represents the hard stop server command
 var serverStopCmd = &cobra.Command{
 	Use:   "stop",
@@ -369,6 +549,8 @@ var serverResizeCmd = &cobra.Command{
 	Long: `
 Resize a server.
 Use: bizfly server resize <server-id> --flavor <flavor name>
+Use: bizfly server resize <server-id> --flavor <flavor name> --safe
+Use: bizfly server resize <server-id> --flavor 2c_4g --category basic --safe
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 1 {
@@ -377,6 +559,23 @@ Use: bizfly server resize <server-id> --flavor <flavor name>
 		}
 		serverID := args[0]
 		client, ctx := getApiClient(cmd)
+		if estimateCost {
+			p := loadPricing()
+			before, after, err := serverResizeCostLines(ctx, client, p, serverID, flavorName, resizeCategory)
+			if err != nil {
+				fmt.Printf("Estimate cost error: %v\n", err)
+				os.Exit(1)
+			}
+			p.printDifference(before, after)
+			return
+		}
+		if resizeSafe {
+			if err := safeResizeServer(ctx, client, serverID, flavorName); err != nil {
+				fmt.Printf("Resize server error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
 		_, err := client.CloudServer.Resize(ctx, serverID, flavorName)
 		if err != nil {
 			fmt.Printf("Resize server error %v\n", err)
@@ -521,43 +720,312 @@ Use: bizfly server rename <server-id> --name <name>
 	},
 }
 
+// parseDataDisk parses a data disk in the size=<size>,type=<HDD|SSD>[,volume-type=<volume type>] format
+func parseDataDisk(diskStr string) (*gobizfly.ServerDisk, error) {
+	disk := &gobizfly.ServerDisk{}
+	for _, pair := range strings.Split(diskStr, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid data disk %q. Use size=<size>,type=<HDD|SSD>", diskStr)
+		}
+		value := kv[1]
+		switch kv[0] {
+		case "size":
+			size, err := strconv.Atoi(value)
+			if err != nil || size <= 0 {
+				return nil, fmt.Errorf("invalid size of data disk %q", diskStr)
+			}
+			disk.Size = size
+		case "type":
+			value = strings.ToUpper(value)
+			if value != "HDD" && value != "SSD" {
+				return nil, fmt.Errorf("invalid type of data disk %q. Use HDD or SSD", diskStr)
+			}
+			disk.Type = &value
+		case "volume-type":
+			disk.VolumeType = &value
+		default:
+			return nil, fmt.Errorf("unknown field %s of data disk %q", kv[0], diskStr)
+		}
+	}
+	if disk.Size == 0 {
+		return nil, fmt.Errorf("missing size of data disk %q", diskStr)
+	}
+	if disk.Type == nil && disk.VolumeType == nil {
+		hdd := "HDD"
+		disk.Type = &hdd
+	}
+	return disk, nil
+}
+
+// getSSHPublicKeys returns the public keys of the SSH keys with the given names
+func getSSHPublicKeys(ctx context.Context, client *gobizfly.Client, names []string) []string {
+	keys, err := client.CloudServer.SSHKeys().List(ctx, &gobizfly.ListOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	var publicKeys []string
+	for _, name := range names {
+		found := false
+		for _, key := range keys {
+			if key.SSHKeyPair.Name == name {
+				publicKeys = append(publicKeys, strings.TrimSpace(key.SSHKeyPair.PublicKey))
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.Fatalf("SSH key %s not found. Use 'bizfly ssh-key list' to get a list of SSH keys", name)
+		}
+	}
+	return publicKeys
+}
+
+// buildUserData reads the user data file, adds the extra SSH public keys to it and returns it base64 encoded.
+// Extra keys need a #cloud-config user data, they are added to its ssh_authorized_keys.
+func buildUserData(path string, extraKeys []string) (string, error) {
+	var userData []byte
+	if path != "" {
+		var err error
+		userData, err = os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(extraKeys) > 0 {
+		var err error
+		if userData, err = addAuthorizedKeys(userData, extraKeys); err != nil {
+			return "", err
+		}
+	}
+	encoded := base64.StdEncoding.EncodeToString(userData)
+	if len(encoded) > maxUserDataSize {
+		return "", fmt.Errorf("user data is %d bytes after base64 encoding, the maximum is %d bytes", len(encoded),
+			maxUserDataSize)
+	}
+	return encoded, nil
+}
+
+// addAuthorizedKeys adds the keys to the ssh_authorized_keys of a #cloud-config user data. The keys are inserted
+// as text, so the comments and the order of the user data are kept. An ssh_authorized_keys which is not a block
+// list is refused.
+func addAuthorizedKeys(userData []byte, keys []string) ([]byte, error) {
+	if len(bytes.TrimSpace(userData)) == 0 {
+		userData = []byte(cloudConfigHeader + "\n")
+	}
+	if !bytes.HasPrefix(userData, []byte(cloudConfigHeader)) {
+		return nil, fmt.Errorf("more than one --ssh-key needs a %s user data file, add the keys to the user data "+
+			"or use one --ssh-key", cloudConfigHeader)
+	}
+	config := make(map[string]interface{})
+	if err := yaml.Unmarshal(userData, &config); err != nil {
+		return nil, err
+	}
+	lines := strings.SplitAfter(string(userData), "\n")
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n"
+	}
+	if _, ok := config["ssh_authorized_keys"]; !ok {
+		lines = append(lines, "ssh_authorized_keys:\n")
+		for _, key := range keys {
+			lines = append(lines, fmt.Sprintf("  - %s\n", key))
+		}
+		return []byte(strings.Join(lines, "")), nil
+	}
+	if _, ok := config["ssh_authorized_keys"].([]interface{}); !ok && config["ssh_authorized_keys"] != nil {
+		return nil, errors.New("ssh_authorized_keys of the user data is not a list")
+	}
+	for i, line := range lines {
+		if !authorizedKeysLineRegexp.MatchString(strings.TrimRight(line, "\r\n")) {
+			continue
+		}
+		// the keys get the indentation of the first item of the list
+		indent := "  - "
+		for _, next := range lines[i+1:] {
+			trimmed := strings.TrimSpace(next)
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			if strings.HasPrefix(trimmed, "- ") {
+				indent = next[:strings.Index(next, "-")+2]
+			}
+			break
+		}
+		var added []string
+		for _, key := range keys {
+			added = append(added, indent+key+"\n")
+		}
+		result := append(append(append([]string{}, lines[:i+1]...), added...), lines[i+1:]...)
+		return []byte(strings.Join(result, "")), nil
+	}
+	return nil, errors.New("ssh_authorized_keys of the user data must be a block list, add the keys to the user data " +
+		"or use one --ssh-key")
+}
+
+// nameMatcher matches server names with a glob pattern or a regular expression between slashes
+type nameMatcher struct {
+	pattern string
+	regex   *regexp.Regexp
+	// plain is true when the pattern has no glob or regular expression syntax. It only matches the exact name,
+	// the API filter is a substring match so it is only used to narrow the servers to list.
+	plain bool
+}
+
+func newNameMatcher(pattern string) (*nameMatcher, error) {
+	m := &nameMatcher{pattern: pattern}
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, err
+		}
+		m.regex = re
+		return m, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	m.plain = !strings.ContainsAny(pattern, "*?[\\")
+	return m, nil
+}
+
+func (m *nameMatcher) Match(name string) bool {
+	if m.pattern == "" {
+		return true
+	}
+	if m.regex != nil {
+		return m.regex.MatchString(name)
+	}
+	matched, _ := path.Match(m.pattern, name)
+	return matched
+}
+
+// serverFilter contains the filters of servers which are not supported by the API
+type serverFilter struct {
+	Status   string
+	Zone     string
+	Flavor   string
+	Category string
+	Names    *nameMatcher
+}
+
+// filterServers applies the filters which are not supported by the API
+func filterServers(servers []*gobizfly.Server, filter *serverFilter) []*gobizfly.Server {
+	var result []*gobizfly.Server
+	for _, server := range servers {
+		if filter.Status != "" && !strings.EqualFold(server.Status, filter.Status) {
+			continue
+		}
+		if filter.Zone != "" && !strings.EqualFold(server.AvailabilityZone, filter.Zone) {
+			continue
+		}
+		if filter.Flavor != "" && !strings.EqualFold(server.FlavorName, filter.Flavor) &&
+			!strings.EqualFold(server.Flavor.Name, filter.Flavor) {
+			continue
+		}
+		if filter.Category != "" && !strings.EqualFold(server.Category, filter.Category) {
+			continue
+		}
+		if filter.Names != nil && !filter.Names.Match(server.Name) {
+			continue
+		}
+		result = append(result, server)
+	}
+	return result
+}
+
+func sortServers(servers []*gobizfly.Server, sortBy string, reverse bool) {
+	if sortBy == "" {
+		return
+	}
+	key := func(server *gobizfly.Server) string {
+		switch sortBy {
+		case "name":
+			return strings.ToLower(server.Name)
+		case "status":
+			return server.Status
+		default:
+			return server.CreatedAt
+		}
+	}
+	sort.SliceStable(servers, func(i, j int) bool {
+		if reverse {
+			return key(servers[i]) > key(servers[j])
+		}
+		return key(servers[i]) < key(servers[j])
+	})
+}
+
+// paginateServers returns the servers of a page. A zero limit returns all servers.
+func paginateServers(servers []*gobizfly.Server, limit, page int) []*gobizfly.Server {
+	if limit == 0 {
+		return servers
+	}
+	start := (page - 1) * limit
+	if start >= len(servers) {
+		return nil
+	}
+	end := start + limit
+	if end > len(servers) {
+		end = len(servers)
+	}
+	return servers[start:end]
+}
+
 func init() {
 	rootCmd.AddCommand(serverCmd)
 	serverCmd.AddCommand(serverListCmd)
+	slpf := serverListCmd.PersistentFlags()
+	slpf.StringVar(&serverFilterStatus, "status", "", "Filter servers by status, e.g. ACTIVE, SHUTOFF, ERROR")
+	slpf.StringVar(&serverFilterZone, "zone", "", "Filter servers by availability zone")
+	slpf.StringVar(&serverFilterFlavor, "flavor", "", "Filter servers by flavor name")
+	slpf.StringVar(&serverFilterName, "name", "", "Filter servers by name. Exact name, glob pattern or regular expression between slashes")
+	slpf.StringVar(&serverFilterIP, "ip", "", "Filter servers by IP address")
+	slpf.StringVar(&serverFilterCategory, "category", "", "Filter servers by category: basic, premium or enterprise")
+	slpf.StringVar(&serverSortBy, "sort-by", "", "Sort servers by created, name or status")
+	slpf.BoolVar(&serverSortReverse, "reverse", false, "Reverse the sort order")
+	slpf.IntVar(&serverListLimit, "limit", 0, "Number of servers per page. Default is all servers")
+	slpf.IntVar(&serverListPage, "page", 1, "Page number, used with --limit")
 	serverCmd.AddCommand(serverGetCmd)
 	serverDeleteCmd.PersistentFlags().BoolVar(&deleteRootDisk, "delete-rootdisk", true, "Delete rootdisk of a server")
 	serverCmd.AddCommand(serverDeleteCmd)
 
 	scpf := serverCreateCmd.PersistentFlags()
 	scpf.StringVar(&serverName, "name", "", "Name of server")
-	_ = cobra.MarkFlagRequired(scpf, "name")
 	scpf.StringVar(&imageID, "image-id", "", "ID of OS image. Create a root disk using this image ID")
 	scpf.StringVar(&volumeID, "volume-id", "", "ID of volume. Create a server using an existing root disk volume.")
 	scpf.StringVar(&snapshotID, "snapshot-id", "", "ID of snapshot. Create a server from a snapshot ID.")
 	scpf.StringVar(&flavorName, "flavor", "", "Name of flavor. Flavor for create a server. Using 'bizfly flavor list' to get a list of flavors")
+	scpf.IntVar(&suggestVCPUs, "vcpus", 1, "Minimum vCPUs of the flavor, used with --flavor auto")
+	scpf.StringVar(&suggestRAM, "ram", "1G", "Minimum RAM of the flavor, e.g. 6G, used with --flavor auto")
 	scpf.StringVar(&networkPlan, "network-plan", "", "Network plan of server (free_bandwidth|free_datatransfer)")
 	scpf.StringArrayVar(&networkInterfaces, "net-interface", []string{}, "Network interface IDs")
 	scpf.StringArrayVar(&firewalls, "firewall", []string{}, "Firewalls IDs")
-	_ = cobra.MarkFlagRequired(scpf, "flavor")
 	scpf.StringVar(&serverCategory, "category", "premium", "Server category: basic, premium or enterprise.")
 	scpf.StringVar(&availabilityZone, "availability-zone", "HN1", "Availability Zone of server.")
 	scpf.StringVar(&rootDiskType, "rootdisk-type", "HDD", "Type of root disk: HDD or SSD.")
 	scpf.StringVar(&rootDiskVolumeType, "rootdisk-volume-type", "", "Type of root disk volume - get from listing volume types: PREMIUM-HDD1")
 	scpf.IntVar(&rootDiskSize, "rootdisk-size", 0, "Size of root disk in Gigabyte. Minimum is 20GB")
-	_ = cobra.MarkFlagRequired(scpf, "rootdisk-size")
-	scpf.StringVar(&sshKey, "ssh-key", "", "SSH key")
+	scpf.StringArrayVar(&sshKeys, "ssh-key", []string{}, "Name of SSH key. Can be repeated, the other keys are added by cloud-init")
+	scpf.StringVar(&userDataFile, "user-data-file", "", "Path of the cloud-init user data file")
+	scpf.StringArrayVar(&dataDisks, "data-disk", []string{}, "Data disk in the size=<size>,type=<HDD|SSD>[,volume-type=<volume type>] format. Can be repeated")
+	scpf.StringArrayVar(&vpcIDs, "vpc-ids", []string{}, "The VPC IDs. Can be repeated")
 	scpf.BoolVar(&isCreatedWan, "is-created-wan-ip", true, "Choose whatever create a WAN IP for server")
 	scpf.StringVar(&billingPlan, "billing-plan", "saving_plan", "Billing plan of server (saving_plan|on_demand)."+
 		" Default is saving_plan")
 
+	addEstimateFlags(serverCreateCmd)
 	serverCmd.AddCommand(serverCreateCmd)
 	serverCmd.AddCommand(serverRebootCmd)
 	serverCmd.AddCommand(serverHardRebootCmd)
+	serverCmd.AddCommand(serverHardCmd)
 	serverCmd.AddCommand(serverStopCmd)
 	serverCmd.AddCommand(serverStartCmd)
 
 	serverResizeCmd.PersistentFlags().StringVar(&flavorName, "flavor", "", "Name of flavor.")
 	_ = cobra.MarkFlagRequired(serverResizeCmd.PersistentFlags(), "flavor")
+	addEstimateFlags(serverResizeCmd)
 	serverCmd.AddCommand(serverResizeCmd)
 
 	serverAddVPCCmd.PersistentFlags().StringArrayVar(&vpcIDs, "vpc-ids", []string{}, "The VPC IDs")
@@ -0,0 +1,188 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+)
+
+const (
+	serverStatusActive = "ACTIVE"
+	serverStatusError  = "ERROR"
+	serverPollInterval = 10 * time.Second
+)
+
+var (
+	serverCount       int
+	serverParallel    int
+	serverCreateWait  bool
+	serverWaitTimeout time.Duration
+	serverBatchHeader = []string{"Name", "Task ID", "Server ID", "Status", "Error"}
+)
+
+// serverCreateResult is the result of creating one server of a batch
+type serverCreateResult struct {
+	Name     string
+	TaskID   string
+	ServerID string
+	Status   string
+	Err      error
+}
+
+// serverNameData is the data of the server name template
+type serverNameData struct {
+	Index int
+}
+
+// serverNames renders the name template for each server of the batch. The index starts from 1.
+func serverNames(nameTemplate string, count int) ([]string, error) {
+	if count > 1 && !strings.Contains(nameTemplate, "{{") {
+		return nil, fmt.Errorf("--name must be a template such as 'web-{{.Index}}' when --count is greater than 1")
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template %q: %v", nameTemplate, err)
+	}
+	names := make([]string, 0, count)
+	seen := make(map[string]bool, count)
+	for i := 1; i <= count; i++ {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, serverNameData{Index: i}); err != nil {
+			return nil, fmt.Errorf("invalid name template %q: %v", nameTemplate, err)
+		}
+		name := buf.String()
+		if seen[name] {
+			return nil, fmt.Errorf("name template %q renders duplicate name %s", nameTemplate, name)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// createServers creates a server for each name with at most serverParallel requests at a time.
+// With --wait, it waits until each server is ACTIVE.
+func createServers(ctx context.Context, client *gobizfly.Client, scr *gobizfly.ServerCreateRequest,
+	names []string) []*serverCreateResult {
+	results := make([]*serverCreateResult, len(names))
+	sem := make(chan struct{}, serverParallel)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		result := &serverCreateResult{Name: name}
+		results[i] = result
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			req := *scr
+			req.Name = result.Name
+			task, err := client.CloudServer.Create(ctx, &req)
+			<-sem
+			if err != nil {
+				result.Err = err
+				return
+			}
+			if len(task.Task) > 0 {
+				result.TaskID = task.Task[0]
+			}
+			result.Status = "CREATING"
+			fmt.Printf("Creating server %s with task id: %s\n", result.Name, result.TaskID)
+			if serverCreateWait {
+				waitServerActive(ctx, client, result)
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// waitServerActive waits for the create task to finish and then for the server to be ACTIVE
+func waitServerActive(ctx context.Context, client *gobizfly.Client, result *serverCreateResult) {
+	deadline := time.Now().Add(serverWaitTimeout)
+	for result.ServerID == "" {
+		task, err := client.CloudServer.GetTask(ctx, result.TaskID)
+		if err == nil && task.Ready {
+			if !task.Result.Success {
+				result.Status = serverStatusError
+				result.Err = fmt.Errorf("task %s failed", result.TaskID)
+				return
+			}
+			result.ServerID = task.Result.ID
+			break
+		}
+		if time.Now().After(deadline) {
+			result.Err = fmt.Errorf("timed out waiting for task %s", result.TaskID)
+			return
+		}
+		time.Sleep(serverPollInterval)
+	}
+	for {
+		server, err := client.CloudServer.Get(ctx, result.ServerID)
+		if err == nil {
+			result.Status = server.Status
+			switch server.Status {
+			case serverStatusActive:
+				return
+			case serverStatusError:
+				result.Err = errors.New("server is in ERROR status")
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			result.Err = fmt.Errorf("timed out waiting for server %s to be ACTIVE", result.ServerID)
+			return
+		}
+		time.Sleep(serverPollInterval)
+	}
+}
+
+// printServerCreateResults prints the results of a batch and returns the number of failed servers
+func printServerCreateResults(results []*serverCreateResult) int {
+	var data [][]string
+	failed := 0
+	for _, r := range results {
+		errMsg := ""
+		if r.Err != nil {
+			failed++
+			errMsg = r.Err.Error()
+			if r.Status == "" {
+				r.Status = "FAILED"
+			}
+		}
+		data = append(data, []string{r.Name, r.TaskID, r.ServerID, r.Status, errMsg})
+	}
+	formatter.Output(serverBatchHeader, data)
+	fmt.Printf("%d succeeded, %d failed\n", len(results)-failed, failed)
+	return failed
+}
+
+func init() {
+	scpf := serverCreateCmd.PersistentFlags()
+	scpf.IntVar(&serverCount, "count", 1, "Number of servers to create. --name is a template such as 'web-{{.Index}}', the index starts from 1")
+	scpf.IntVar(&serverParallel, "parallel", 5, "Maximum number of create requests sent at a time")
+	scpf.BoolVar(&serverCreateWait, "wait", false, "Wait until the servers are ACTIVE")
+	scpf.DurationVar(&serverWaitTimeout, "wait-timeout", 15*time.Minute, "Maximum time to wait for the servers, used with --wait")
+}
@@ -0,0 +1,50 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestServerNames(t *testing.T) {
+	tests := []struct {
+		template string
+		count    int
+		want     []string
+		wantErr  bool
+	}{
+		{template: "web", count: 1, want: []string{"web"}},
+		{template: "web-{{.Index}}", count: 3, want: []string{"web-1", "web-2", "web-3"}},
+		{template: `web-{{printf "%02d" .Index}}`, count: 2, want: []string{"web-01", "web-02"}},
+		{template: "web", count: 2, wantErr: true},
+		{template: "web-{{.Index", count: 2, wantErr: true},
+		{template: "web-{{.Zone}}", count: 2, wantErr: true},
+		{template: "web-{{if .Index}}x{{end}}", count: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := serverNames(tt.template, tt.count)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("serverNames(%q, %d) expected an error", tt.template, tt.count)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("serverNames(%q, %d) = %v, %v, want %v", tt.template, tt.count, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+	"github.com/spf13/cobra"
+)
+
+const (
+	snapshotStatusAvailable = "available"
+	snapshotStatusError     = "error"
+)
+
+var (
+	cloneServerName     string
+	cloneVolumes        bool
+	cloneKeepSnapshots  bool
+	cloneWait           bool
+	cloneWaitTimeout    time.Duration
+	snapshotWaitTimeout time.Duration
+	cloneSnapshotHeader = []string{"Snapshot ID", "Source Volume ID", "Result"}
+)
+
+// serverCloneCmd represents the server clone command
+var serverCloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Clone a server",
+	Long: `Create a new server from a snapshot of the root disk of a server, with the same flavor, category, zone,
+firewalls, VPCs and SSH key. With --clone-volumes, the attached data volumes are cloned and attached to the new server.
+The snapshots of the root disk and the data volumes are taken together before the new server is created.
+The snapshots are deleted once the new server and volumes are ready, so the command waits for them.
+With --keep-snapshots the snapshots are kept and reported, and the command only waits with --wait or --clone-volumes.
+Example: bizfly server clone <server-id> --name web-2
+Example: bizfly server clone <server-id> --name web-2 --clone-volumes
+Example: bizfly server clone <server-id> --name web-2 --keep-snapshots
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify server-id in the command. Use bizfly server clone <server-id> --name <name>")
+			os.Exit(1)
+		}
+		if len(args) > 1 {
+			fmt.Printf("Unknow variable %s", strings.Join(args[1:], ""))
+		}
+		client, ctx := getApiClient(cmd)
+		source, err := client.CloudServer.Get(ctx, args[0])
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Server %s not found.\n", args[0])
+				os.Exit(1)
+			}
+			log.Fatal(err)
+		}
+		var rootDisk *gobizfly.AttachedVolume
+		var dataVolumes []gobizfly.AttachedVolume
+		for i, volume := range source.AttachedVolumes {
+			if volume.AttachedType == attachTypeRootDisk {
+				rootDisk = &source.AttachedVolumes[i]
+			} else {
+				dataVolumes = append(dataVolumes, volume)
+			}
+		}
+		if rootDisk == nil {
+			fmt.Printf("Server %s has no root disk\n", source.ID)
+			os.Exit(1)
+		}
+		rootVolume, err := client.CloudServer.Volumes().Get(ctx, rootDisk.ID)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		volumes := []*gobizfly.Volume{rootVolume}
+		names := []string{cloneServerName + "-rootdisk"}
+		if cloneVolumes {
+			for _, dataVolume := range dataVolumes {
+				volume, err := client.CloudServer.Volumes().Get(ctx, dataVolume.ID)
+				if err != nil {
+					log.Fatal(err)
+				}
+				volumes = append(volumes, volume)
+				names = append(names, cloneServerName+"-"+volume.Name)
+			}
+		}
+		snapshots, err := snapshotVolumes(ctx, client, volumes, names)
+		if err != nil {
+			printCloneSnapshots(ctx, client, snapshots)
+			log.Fatal(err)
+		}
+		scr, err := cloneServerCreateRequest(ctx, client, source, rootVolume, snapshots[0].ID)
+		if err != nil {
+			printCloneSnapshots(ctx, client, snapshots)
+			log.Fatal(err)
+		}
+
+		// the server is created by the create server code, with the wait settings of this command
+		serverCreateWait = cloneWait || !cloneKeepSnapshots || len(volumes) > 1
+		serverWaitTimeout = cloneWaitTimeout
+		serverParallel = 1
+		results := createServers(ctx, client, scr, []string{cloneServerName})
+		if printServerCreateResults(results) > 0 {
+			printCloneSnapshots(ctx, client, snapshots)
+			os.Exit(1)
+		}
+		snapshots[0].Ready = serverCreateWait
+
+		if len(volumes) > 1 {
+			var data [][]string
+			for i, volume := range volumes[1:] {
+				cs := snapshots[i+1]
+				clone, err := client.CloudServer.Volumes().Create(ctx, &gobizfly.VolumeCreateRequest{
+					Name:             cloneServerName + "-" + volume.Name,
+					Size:             volume.Size,
+					VolumeType:       volume.VolumeType,
+					VolumeCategory:   volume.Category,
+					AvailabilityZone: volume.AvailabilityZone,
+					SnapshotID:       cs.ID,
+					ServerID:         results[0].ServerID,
+					BillingPlan:      volume.BillingPlan,
+				})
+				if err != nil {
+					printCloneSnapshots(ctx, client, snapshots)
+					log.Fatalf("Clone volume %s error: %v", volume.ID, err)
+				}
+				status := clone.Status
+				if ready, err := waitVolumeStatus(ctx, client, clone.ID, volumeStatusInUse, cloneWaitTimeout); err != nil {
+					fmt.Printf("Clone volume %s error: %v\n", volume.ID, err)
+				} else {
+					status = ready.Status
+					cs.Ready = true
+				}
+				data = append(data, []string{clone.ID, clone.Name, status, strconv.Itoa(clone.Size), clone.VolumeType,
+					volume.ID, cs.ID})
+			}
+			formatter.Output([]string{"ID", "Name", "Status", "Size", "Type", "Source Volume ID", "Snapshot ID"}, data)
+		}
+		if printCloneSnapshots(ctx, client, snapshots) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// cloneSnapshot is a snapshot made by the clone. Ready is true once the server or volume created from it is ready.
+type cloneSnapshot struct {
+	ID       string
+	VolumeID string
+	Ready    bool
+}
+
+// printCloneSnapshots deletes the snapshots whose server or volume is ready, unless --keep-snapshots is given,
+// and prints what happened to each snapshot. It returns the number of snapshots which could not be deleted.
+func printCloneSnapshots(ctx context.Context, client *gobizfly.Client, snapshots []*cloneSnapshot) int {
+	var data [][]string
+	failed := 0
+	for _, snapshot := range snapshots {
+		result := "deleted"
+		switch {
+		case cloneKeepSnapshots:
+			result = "kept"
+		case !snapshot.Ready:
+			result = "kept, its clone is not ready"
+			failed++
+		default:
+			if err := client.CloudServer.Snapshots().Delete(ctx, snapshot.ID); err != nil {
+				result = fmt.Sprintf("delete error: %v", err)
+				failed++
+			}
+		}
+		data = append(data, []string{snapshot.ID, snapshot.VolumeID, result})
+	}
+	formatter.Output(cloneSnapshotHeader, data)
+	return failed
+}
+
+// cloneServerCreateRequest returns the request to create a server like the source server from the root disk snapshot
+func cloneServerCreateRequest(ctx context.Context, client *gobizfly.Client, source *gobizfly.Server,
+	rootVolume *gobizfly.Volume, snapshotID string) (*gobizfly.ServerCreateRequest, error) {
+	fws, err := client.CloudServer.Firewalls().List(ctx, &gobizfly.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var firewallIDs []string
+	for _, fw := range fws {
+		if _, ok := SliceContains(fw.Servers, source.ID); ok {
+			firewallIDs = append(firewallIDs, fw.ID)
+		}
+	}
+
+	vpcs, err := client.CloudServer.VPCNetworks().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var allVPCIDs []string
+	for _, vpc := range vpcs {
+		allVPCIDs = append(allVPCIDs, vpc.ID)
+	}
+	nics, err := client.CloudServer.NetworkInterfaces().List(ctx, &gobizfly.ListNetworkInterfaceOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var serverVPCIDs []string
+	for _, nic := range nics {
+		if nic.DeviceID != source.ID {
+			continue
+		}
+		if _, ok := SliceContains(allVPCIDs, nic.NetworkID); !ok {
+			continue
+		}
+		if _, ok := SliceContains(serverVPCIDs, nic.NetworkID); !ok {
+			serverVPCIDs = append(serverVPCIDs, nic.NetworkID)
+		}
+	}
+
+	rootDisk := &gobizfly.ServerDisk{Size: rootVolume.Size}
+	if rootVolume.VolumeType != "" {
+		rootDisk.VolumeType = &rootVolume.VolumeType
+	} else {
+		rootDisk.Type = &rootVolume.Type
+	}
+	isCreatedWan := len(source.IPAddresses.WanV4Addresses) > 0
+	if source.IsCreatedWan != nil {
+		isCreatedWan = *source.IsCreatedWan
+	}
+	return &gobizfly.ServerCreateRequest{
+		Name:             cloneServerName,
+		FlavorName:       source.FlavorName,
+		SSHKey:           source.KeyName,
+		RootDisk:         rootDisk,
+		Type:             source.Category,
+		AvailabilityZone: source.AvailabilityZone,
+		OS:               &gobizfly.ServerOS{Type: "snapshot", ID: snapshotID},
+		NetworkPlan:      source.NetworkPlan,
+		Firewalls:        firewallIDs,
+		VPCNetworkIds:    serverVPCIDs,
+		BillingPlan:      source.BillingPlan,
+		IsCreatedWan:     &isCreatedWan,
+	}, nil
+}
+
+// snapshotVolumes creates the snapshots of the volumes one after the other, then waits until they are available.
+// The snapshots created so far are returned with the error, so they can be reported.
+func snapshotVolumes(ctx context.Context, client *gobizfly.Client, volumes []*gobizfly.Volume,
+	names []string) ([]*cloneSnapshot, error) {
+	var snapshots []*cloneSnapshot
+	for i, volume := range volumes {
+		snapshot, err := createSnapshot(ctx, client, volume.ID, names[i])
+		if err != nil {
+			return snapshots, err
+		}
+		snapshots = append(snapshots, &cloneSnapshot{ID: snapshot.Id, VolumeID: volume.ID})
+	}
+	for _, snapshot := range snapshots {
+		if _, err := waitSnapshotAvailable(ctx, client, snapshot.ID, snapshotWaitTimeout); err != nil {
+			return snapshots, err
+		}
+	}
+	return snapshots, nil
+}
+
+// createSnapshot creates a snapshot of a volume
+func createSnapshot(ctx context.Context, client *gobizfly.Client, volumeID, name string) (*gobizfly.Snapshot, error) {
+	snapshot, err := client.CloudServer.Snapshots().Create(ctx, &gobizfly.SnapshotCreateRequest{
+		Name:     name,
+		VolumeId: volumeID,
+		Force:    true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create snapshot for volume %s error: %w", volumeID, err)
+	}
+	fmt.Printf("Creating snapshot %s of volume %s\n", snapshot.Id, volumeID)
+	return snapshot, nil
+}
+
+// snapshotAndWait creates a snapshot of a volume and waits until it is available
+func snapshotAndWait(ctx context.Context, client *gobizfly.Client, volumeID, name string) *gobizfly.Snapshot {
+	snapshot, err := createSnapshot(ctx, client, volumeID, name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	available, err := waitSnapshotAvailable(ctx, client, snapshot.Id, snapshotWaitTimeout)
+	if err != nil {
+		log.Fatalf("%v. Snapshot %s is kept", err, snapshot.Id)
+	}
+	return available
+}
+
+// waitSnapshotAvailable waits until the snapshot is available
+func waitSnapshotAvailable(ctx context.Context, client *gobizfly.Client, id string, timeout time.Duration) (*gobizfly.Snapshot, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		snapshot, err := client.CloudServer.Snapshots().Get(ctx, id)
+		if err == nil {
+			switch strings.ToLower(snapshot.Status) {
+			case snapshotStatusAvailable:
+				return snapshot, nil
+			case snapshotStatusError:
+				return nil, fmt.Errorf("snapshot %s is in error status", id)
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for snapshot %s to be available", id)
+		}
+		time.Sleep(serverPollInterval)
+	}
+}
+
+func init() {
+	serverCmd.AddCommand(serverCloneCmd)
+	sclpf := serverCloneCmd.PersistentFlags()
+	sclpf.StringVar(&cloneServerName, "name", "", "Name of the new server")
+	_ = cobra.MarkFlagRequired(sclpf, "name")
+	sclpf.BoolVar(&cloneVolumes, "clone-volumes", false, "Clone the attached data volumes and attach them to the new server")
+	sclpf.BoolVar(&cloneKeepSnapshots, "keep-snapshots", false, "Keep the snapshots of the root disk and data volumes")
+	sclpf.BoolVar(&cloneWait, "wait", false, "Wait until the new server is ACTIVE, used with --keep-snapshots")
+	sclpf.DurationVar(&cloneWaitTimeout, "wait-timeout", 15*time.Minute, "Maximum time to wait for the new server and each new volume")
+	sclpf.DurationVar(&snapshotWaitTimeout, "snapshot-timeout", 30*time.Minute, "Maximum time to wait for each snapshot")
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/bizflycloud/gobizfly"
+	"github.com/spf13/cobra"
+)
+
+var consoleOpen bool
+
+// serverConsoleCmd represents the server console command
+var serverConsoleCmd = &cobra.Command{
+	Use:   "console",
+	Short: "Get the VNC console URL of a server",
+	Long: `Print the noVNC console URL of a server, or open it in the browser with --open
+Example: bizfly server console <server-id>
+Example: bizfly server console <server-id> --open
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify server-id in the command. Use bizfly server console <server-id>")
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		console, err := client.CloudServer.GetVNC(ctx, args[0])
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Server %s not found.\n", args[0])
+				os.Exit(1)
+			}
+			log.Fatal(err)
+		}
+		fmt.Println(console.URL)
+		if consoleOpen {
+			if err := openBrowser(console.URL); err != nil {
+				fmt.Printf("Open console in the browser error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func openBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
+
+func init() {
+	serverCmd.AddCommand(serverConsoleCmd)
+	serverConsoleCmd.PersistentFlags().BoolVar(&consoleOpen, "open", false, "Open the console URL in the browser")
+
+}
@@ -0,0 +1,263 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serverDescribeOutput string
+
+	serverDescribeGeneralHeader   = []string{"Field", "Value"}
+	serverDescribeIPHeader        = []string{"Type", "Address", "Version", "Interface ID"}
+	serverDescribeVolumeHeader    = []string{"ID", "Name", "Size", "Type", "Category", "Attached Type"}
+	serverDescribeFirewallHeader  = []string{"ID", "Name", "Description"}
+	serverDescribeInterfaceHeader = []string{"ID", "Name", "Type", "Network ID", "Status", "MAC Address", "IP Addresses"}
+)
+
+// serverDetail contains a server with its firewalls and network interfaces
+type serverDetail struct {
+	Server            *gobizfly.Server                              `json:"server"`
+	Firewalls         []*gobizfly.Firewall                          `json:"firewalls"`
+	NetworkInterfaces []*gobizfly.NetworkInterface                  `json:"network_interfaces"`
+	WanInterfaces     []*gobizfly.CloudServerPublicNetworkInterface `json:"wan_interfaces"`
+}
+
+// serverDescribeCmd represents the server describe command
+var serverDescribeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "Describe a server",
+	Long: `Describe a server with sections for general information, IP addresses, volumes, firewalls and network interfaces
+Example: bizfly server describe fd554aac-9ab1-11ea-b09d-bbaf82f02f58
+Example: bizfly server describe fd554aac-9ab1-11ea-b09d-bbaf82f02f58 --output json
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify server-id in the command. Use bizfly server describe <server-id>")
+			os.Exit(1)
+		}
+		if len(args) > 1 {
+			fmt.Printf("Unknow variable %s", strings.Join(args[1:], ""))
+		}
+		if serverDescribeOutput != "table" && serverDescribeOutput != "json" {
+			fmt.Printf("Invalid output format %s. Use table or json\n", serverDescribeOutput)
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		detail, err := getServerDetail(ctx, client, args[0])
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Server %s not found.", args[0])
+				return
+			}
+			log.Fatal(err)
+		}
+		if serverDescribeOutput == "json" {
+			if err := formatter.JSONOutput(detail); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		detail.print()
+	},
+}
+
+// getServerDetail fetches the server, firewalls, network interfaces and WAN IPs concurrently
+func getServerDetail(ctx context.Context, client *gobizfly.Client, serverID string) (*serverDetail, error) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		detail   serverDetail
+		fws      []*gobizfly.Firewall
+		nics     []*gobizfly.NetworkInterface
+		wans     []*gobizfly.CloudServerPublicNetworkInterface
+	)
+	setErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		var err error
+		if detail.Server, err = client.CloudServer.Get(ctx, serverID); err != nil {
+			setErr(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		if fws, err = client.CloudServer.Firewalls().List(ctx, &gobizfly.ListOptions{}); err != nil {
+			setErr(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		if nics, err = client.CloudServer.NetworkInterfaces().List(ctx, &gobizfly.ListNetworkInterfaceOptions{}); err != nil {
+			setErr(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		if wans, err = client.CloudServer.PublicNetworkInterfaces().List(ctx); err != nil {
+			setErr(err)
+		}
+	}()
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	for _, fw := range fws {
+		if _, ok := SliceContains(fw.Servers, serverID); ok {
+			detail.Firewalls = append(detail.Firewalls, fw)
+		}
+	}
+	for _, nic := range nics {
+		if nic.DeviceID == serverID {
+			detail.NetworkInterfaces = append(detail.NetworkInterfaces, nic)
+		}
+	}
+	for _, wan := range wans {
+		if wan.DeviceID == serverID && !detail.hasNetworkInterface(wan.ID) {
+			detail.WanInterfaces = append(detail.WanInterfaces, wan)
+		}
+	}
+	return &detail, nil
+}
+
+func (d *serverDetail) hasNetworkInterface(id string) bool {
+	for _, nic := range d.NetworkInterfaces {
+		if nic.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaceID returns the ID of the network interface or WAN IP which has the address
+func (d *serverDetail) interfaceID(address string) string {
+	for _, nic := range d.NetworkInterfaces {
+		for _, ip := range nic.FixedIps {
+			if ip.IPAddress == address {
+				return nic.ID
+			}
+		}
+	}
+	for _, wan := range d.WanInterfaces {
+		if wan.IpAddress == address {
+			return wan.ID
+		}
+		for _, ip := range wan.FixedIps {
+			if ip.IPAddress == address {
+				return wan.ID
+			}
+		}
+	}
+	return ""
+}
+
+func (d *serverDetail) print() {
+	s := d.Server
+	printSection("General", serverDescribeGeneralHeader, [][]string{
+		{"ID", s.ID},
+		{"Name", s.Name},
+		{"Status", s.Status},
+		{"Zone", s.AvailabilityZone},
+		{"Region", s.RegionName},
+		{"Category", s.Category},
+		{"Flavor", s.FlavorName},
+		{"vCPUs", strconv.Itoa(s.Flavor.VCPU)},
+		{"RAM (MB)", strconv.Itoa(s.Flavor.Ram)},
+		{"Key Name", s.KeyName},
+		{"Locked", strconv.FormatBool(s.Locked)},
+		{"Billing Plan", s.BillingPlan},
+		{"Network Plan", s.NetworkPlan},
+		{"Created At", s.CreatedAt},
+		{"Updated At", s.UpdatedAt},
+	})
+
+	var ips [][]string
+	addIPs := func(ipType string, addresses []gobizfly.IP) {
+		for _, ip := range addresses {
+			ips = append(ips, []string{ipType, ip.Address, strconv.Itoa(ip.Version), d.interfaceID(ip.Address)})
+		}
+	}
+	addIPs("LAN", s.IPAddresses.LanAddresses)
+	addIPs("WAN v4", s.IPAddresses.WanV4Addresses)
+	addIPs("WAN v6", s.IPAddresses.WanV6Addresses)
+	printSection("IP Addresses", serverDescribeIPHeader, ips)
+
+	var volumes [][]string
+	for _, v := range s.AttachedVolumes {
+		volumes = append(volumes, []string{v.ID, v.Name, strconv.Itoa(v.Size), v.Type, v.Category, v.AttachedType})
+	}
+	printSection("Volumes", serverDescribeVolumeHeader, volumes)
+
+	var firewalls [][]string
+	for _, fw := range d.Firewalls {
+		firewalls = append(firewalls, []string{fw.ID, fw.Name, fw.Description})
+	}
+	printSection("Firewalls", serverDescribeFirewallHeader, firewalls)
+
+	var interfaces [][]string
+	for _, nic := range d.NetworkInterfaces {
+		var addresses []string
+		for _, ip := range nic.FixedIps {
+			addresses = append(addresses, ip.IPAddress)
+		}
+		interfaces = append(interfaces, []string{nic.ID, nic.Name, nic.Type, nic.NetworkID, nic.Status, nic.MacAddress,
+			strings.Join(addresses, ", ")})
+	}
+	for _, wan := range d.WanInterfaces {
+		interfaces = append(interfaces, []string{wan.ID, wan.Name, "wan", wan.NetworkID, wan.Status, wan.MacAddress,
+			wan.IpAddress})
+	}
+	printSection("Network Interfaces", serverDescribeInterfaceHeader, interfaces)
+}
+
+func printSection(title string, header []string, data [][]string) {
+	fmt.Println(title)
+	if len(data) == 0 {
+		fmt.Println("None")
+	} else {
+		formatter.Output(header, data)
+	}
+	fmt.Println()
+}
+
+func init() {
+	serverCmd.AddCommand(serverDescribeCmd)
+	serverDescribeCmd.PersistentFlags().StringVarP(&serverDescribeOutput, "output", "o", "table", "Output format (table, json)")
+}
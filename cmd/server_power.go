@@ -0,0 +1,233 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+	"github.com/spf13/cobra"
+)
+
+const serverStatusShutoff = "SHUTOFF"
+
+var (
+	serverSelectors   []string
+	serverPowerHeader = []string{"ID", "Name", "Action", "Result", "Error"}
+)
+
+// serverPowerActions maps the power actions to the API calls
+var serverPowerActions = map[string]func(ctx context.Context, client *gobizfly.Client, id string) error{
+	"on": func(ctx context.Context, client *gobizfly.Client, id string) error {
+		_, err := client.CloudServer.Start(ctx, id)
+		return err
+	},
+	"off": func(ctx context.Context, client *gobizfly.Client, id string) error {
+		_, err := client.CloudServer.Stop(ctx, id)
+		return err
+	},
+	"reboot": func(ctx context.Context, client *gobizfly.Client, id string) error {
+		_, err := client.CloudServer.SoftReboot(ctx, id)
+		return err
+	},
+	"hard-reboot": func(ctx context.Context, client *gobizfly.Client, id string) error {
+		_, err := client.CloudServer.HardReboot(ctx, id)
+		return err
+	},
+}
+
+// serverPowerCmd represents the server power command
+var serverPowerCmd = &cobra.Command{
+	Use:   "power",
+	Short: "Power on, power off or reboot servers",
+	Long: `Power on, power off, reboot or hard reboot servers given by IDs or by a selector.
+The selector is a comma separated list of key=value with keys name, zone, status, category and flavor.
+--selector can be repeated, the servers must match all selectors.
+Name is an exact name, a glob pattern (web-*) or a regular expression between slashes, which may contain commas.
+Example: bizfly server power off <server-id> <server-id>
+Example: bizfly server power reboot --selector name=web-*,zone=HN1 --parallel 10
+Example: bizfly server power off --selector 'name=/^web-[0-9]{2,3}$/' --selector status=ACTIVE
+`,
+	ValidArgs: []string{"on", "off", "reboot", "hard-reboot"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify the action in the command. Use bizfly server power on|off|reboot|hard-reboot <server-id>...")
+			os.Exit(1)
+		}
+		action, ok := serverPowerActions[args[0]]
+		if !ok {
+			fmt.Printf("Invalid action %s. Use on, off, reboot or hard-reboot\n", args[0])
+			os.Exit(1)
+		}
+		if len(args) < 2 && len(serverSelectors) == 0 {
+			fmt.Println("You need to specify server IDs or --selector")
+			os.Exit(1)
+		}
+		if serverParallel < 1 {
+			fmt.Println("--parallel must be greater than 0")
+			os.Exit(1)
+		}
+		filter, opts, err := parseServerSelectors(serverSelectors)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		servers := make([]*gobizfly.Server, 0, len(args)-1)
+		for _, id := range args[1:] {
+			servers = append(servers, &gobizfly.Server{ID: id})
+		}
+		if len(serverSelectors) > 0 {
+			selected, err := client.CloudServer.List(ctx, opts)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			selected = filterServers(selected, filter)
+			if len(selected) == 0 {
+				fmt.Printf("No server matches selector %s\n", strings.Join(serverSelectors, " "))
+				os.Exit(1)
+			}
+			for _, server := range selected {
+				if _, ok := SliceContains(args[1:], server.ID); !ok {
+					servers = append(servers, server)
+				}
+			}
+		}
+
+		data := make([][]string, len(servers))
+		failed := 0
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, serverParallel)
+		for i, server := range servers {
+			i, server := i, server
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				row := []string{server.ID, server.Name, args[0], "", ""}
+				data[i] = row
+				if (args[0] == "on" && server.Status == serverStatusActive) ||
+					(args[0] == "off" && server.Status == serverStatusShutoff) {
+					row[3] = "skipped, already " + server.Status
+					return
+				}
+				sem <- struct{}{}
+				err := action(ctx, client, server.ID)
+				<-sem
+				if err != nil {
+					row[3], row[4] = "failed", err.Error()
+					mu.Lock()
+					failed++
+					mu.Unlock()
+					return
+				}
+				row[3] = "accepted"
+			}()
+		}
+		wg.Wait()
+		formatter.Output(serverPowerHeader, data)
+		fmt.Printf("%d succeeded, %d failed\n", len(servers)-failed, failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// serverSelectorKeys are the keys of a server selector
+var serverSelectorKeys = []string{"name", "zone", "status", "category", "flavor"}
+
+// parseServerSelectors parses selectors such as name=web-*,zone=HN1 into the filter and the list options.
+// A comma only separates pairs when it is followed by a selector key, so regular expressions may contain commas.
+// The selectors are combined, and each key may only be given once.
+func parseServerSelectors(selectors []string) (*serverFilter, *gobizfly.ServerListOptions, error) {
+	filter := &serverFilter{}
+	opts := &gobizfly.ServerListOptions{}
+	var keys []string
+	for _, selector := range selectors {
+		for _, pair := range splitServerSelector(selector) {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || kv[1] == "" {
+				return nil, nil, fmt.Errorf("invalid selector %q. Use key=value pairs separated by commas", selector)
+			}
+			if _, ok := SliceContains(keys, kv[0]); ok {
+				return nil, nil, fmt.Errorf("selector key %s is given more than once", kv[0])
+			}
+			keys = append(keys, kv[0])
+			switch kv[0] {
+			case "name":
+				names, err := newNameMatcher(kv[1])
+				if err != nil {
+					return nil, nil, fmt.Errorf("invalid name in selector %s: %v", kv[1], err)
+				}
+				if names.plain {
+					opts.Name = kv[1]
+				}
+				filter.Names = names
+			case "zone":
+				filter.Zone = kv[1]
+			case "status":
+				filter.Status = kv[1]
+				opts.Status = strings.ToUpper(kv[1])
+			case "category":
+				filter.Category = kv[1]
+			case "flavor":
+				filter.Flavor = kv[1]
+			default:
+				return nil, nil, fmt.Errorf("unknown selector key %s. Use name, zone, status, category or flavor", kv[0])
+			}
+		}
+	}
+	return filter, opts, nil
+}
+
+// splitServerSelector splits a selector at the commas. A comma inside a name regular expression is kept
+// when it is not followed by a selector key and "=".
+func splitServerSelector(selector string) []string {
+	var pairs []string
+	for _, part := range strings.Split(selector, ",") {
+		isPair := false
+		for _, key := range serverSelectorKeys {
+			if strings.HasPrefix(part, key+"=") {
+				isPair = true
+				break
+			}
+		}
+		if !isPair && len(pairs) > 0 && inNameRegexp(pairs[len(pairs)-1]) {
+			pairs[len(pairs)-1] += "," + part
+		} else {
+			pairs = append(pairs, part)
+		}
+	}
+	return pairs
+}
+
+// inNameRegexp returns true when the pair is a name regular expression without its closing slash
+func inNameRegexp(pair string) bool {
+	return strings.HasPrefix(pair, "name=/") && (pair == "name=/" || !strings.HasSuffix(pair, "/"))
+}
+
+func init() {
+	serverCmd.AddCommand(serverPowerCmd)
+	sppf := serverPowerCmd.PersistentFlags()
+	sppf.StringArrayVar(&serverSelectors, "selector", []string{}, "Select servers by name, zone, status, category and flavor, e.g. name=web-*,zone=HN1. Can be repeated")
+	sppf.IntVar(&serverParallel, "parallel", 5, "Maximum number of servers processed at a time")
+}
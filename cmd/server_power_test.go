@@ -0,0 +1,71 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitServerSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{selector: "name=web-*", want: []string{"name=web-*"}},
+		{selector: "name=web-*,zone=HN1", want: []string{"name=web-*", "zone=HN1"}},
+		{selector: "name=/^web-[0-9]{2,3}$/,zone=HN1", want: []string{"name=/^web-[0-9]{2,3}$/", "zone=HN1"}},
+		{selector: "zone=HN1,name=/a,b,c/", want: []string{"zone=HN1", "name=/a,b,c/"}},
+		{selector: "zone=HN1,foo=bar", want: []string{"zone=HN1", "foo=bar"}},
+		{selector: "name=web-*,foo=bar", want: []string{"name=web-*", "foo=bar"}},
+		{selector: "name=/a/,b", want: []string{"name=/a/", "b"}},
+	}
+	for _, tt := range tests {
+		if got := splitServerSelector(tt.selector); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitServerSelector(%q) = %q, want %q", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestParseServerSelectors(t *testing.T) {
+	filter, opts, err := parseServerSelectors([]string{"name=/^web-[0-9]{2,3}$/,zone=HN1", "status=active"})
+	if err != nil {
+		t.Fatalf("parseServerSelectors error: %v", err)
+	}
+	if filter.Zone != "HN1" || filter.Status != "active" || opts.Status != "ACTIVE" || opts.Name != "" {
+		t.Errorf("parseServerSelectors = %+v, %+v", filter, opts)
+	}
+	if !filter.Names.Match("web-123") || filter.Names.Match("web-1") {
+		t.Errorf("parseServerSelectors name matcher does not match the regular expression")
+	}
+
+	filter, opts, err = parseServerSelectors([]string{"name=web-1,category=premium,flavor=2c_4g"})
+	if err != nil {
+		t.Fatalf("parseServerSelectors error: %v", err)
+	}
+	if opts.Name != "web-1" || filter.Category != "premium" || filter.Flavor != "2c_4g" {
+		t.Errorf("parseServerSelectors = %+v, %+v", filter, opts)
+	}
+
+	for _, selector := range []string{"zone", "zone=", "region=HN", "zone=HN1,foo=bar", "name=web-["} {
+		if _, _, err := parseServerSelectors([]string{selector}); err == nil {
+			t.Errorf("parseServerSelectors(%q) expected an error", selector)
+		}
+	}
+	if _, _, err := parseServerSelectors([]string{"zone=HN1", "zone=HN2"}); err == nil {
+		t.Errorf("parseServerSelectors with a repeated key expected an error")
+	}
+}
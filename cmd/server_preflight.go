@@ -0,0 +1,305 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bizflycloud/gobizfly"
+)
+
+const minRootDiskSize = 20
+
+var (
+	skipPreflight bool
+
+	shortFlavorNameRegexp = regexp.MustCompile(`(\d+c_\d+g)`)
+	serverCategories      = []string{"basic", "premium", "enterprise"}
+	rootDiskTypes         = []string{"HDD", "SSD"}
+)
+
+// preflightServerCreate checks the create server request against the flavor, image and volume type catalogs
+// and returns the list of problems found. A short flavor name is replaced with the full name of the flavor.
+func preflightServerCreate(ctx context.Context, client *gobizfly.Client, scr *gobizfly.ServerCreateRequest) ([]string, error) {
+	var problems []string
+	// the root disk of a server created from a volume or a snapshot has the size of the source
+	if (scr.OS == nil || scr.OS.Type == "image") && scr.RootDisk.Size < minRootDiskSize {
+		problems = append(problems, fmt.Sprintf("root disk size %dGB is less than the minimum %dGB", scr.RootDisk.Size,
+			minRootDiskSize))
+	}
+	if _, ok := SliceContains(serverCategories, scr.Type); !ok {
+		problems = append(problems, fmt.Sprintf("category %s is invalid%s", scr.Type, didYouMean(scr.Type, serverCategories)))
+	}
+	if scr.RootDisk.Type != nil {
+		if _, ok := SliceContains(rootDiskTypes, *scr.RootDisk.Type); !ok {
+			problems = append(problems, fmt.Sprintf("root disk type %s is invalid%s", *scr.RootDisk.Type,
+				didYouMean(*scr.RootDisk.Type, rootDiskTypes)))
+		}
+	}
+
+	flavorName, flavorProblems, err := resolveFlavor(ctx, client, scr.FlavorName, scr.Type)
+	if err != nil {
+		return nil, err
+	}
+	if len(flavorProblems) == 0 {
+		scr.FlavorName = flavorName
+	}
+	problems = append(problems, flavorProblems...)
+
+	if scr.OS != nil && scr.OS.Type == "image" {
+		imageProblems, err := preflightImage(ctx, client, scr.OS.ID)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, imageProblems...)
+	}
+
+	volumeTypeProblems, err := preflightVolumeTypes(ctx, client, scr)
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, volumeTypeProblems...)
+	return problems, nil
+}
+
+// resolveFlavor checks that the flavor exists in the category and returns its full name. The flavor may be given
+// by its full name such as nix.2c_4g or by its short name such as 2c_4g as printed by 'bizfly flavor list'.
+// A short name is only resolved when a single flavor of the category has it.
+func resolveFlavor(ctx context.Context, client *gobizfly.Client, name, category string) (string, []string, error) {
+	flavors, err := client.CloudServer.Flavors().List(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, flavor := range flavors {
+		if flavor.Name == name && flavor.Category == category {
+			return flavor.Name, nil, nil
+		}
+	}
+	short := shortFlavorName(name)
+	var matches, candidates, categories []string
+	for _, flavor := range flavors {
+		matched := flavor.Name == name || (short == name && shortFlavorName(flavor.Name) == short)
+		if matched && flavor.Category == category {
+			matches = append(matches, flavor.Name)
+		} else if matched {
+			if _, ok := SliceContains(categories, flavor.Category); !ok {
+				categories = append(categories, flavor.Category)
+			}
+		}
+		if flavor.Category == category {
+			candidates = append(candidates, flavor.Name)
+			if s := shortFlavorName(flavor.Name); s != "" && s != flavor.Name {
+				candidates = append(candidates, s)
+			}
+		}
+	}
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil, nil
+	case len(matches) > 1:
+		sort.Strings(matches)
+		return "", []string{fmt.Sprintf("flavor %s is ambiguous in category %s, use one of: %s", name, category,
+			strings.Join(matches, ", "))}, nil
+	case len(categories) > 0:
+		sort.Strings(categories)
+		return "", []string{fmt.Sprintf("flavor %s is not available in category %s, it is available in: %s", name,
+			category, strings.Join(categories, ", "))}, nil
+	}
+	return "", []string{fmt.Sprintf("flavor %s not found in category %s%s. Use 'bizfly flavor list' to get a list of flavors",
+		name, category, didYouMean(name, candidates))}, nil
+}
+
+// preflightImage checks that the image ID is an OS image or a custom image. When the image is given by name,
+// the ID of the image is suggested.
+func preflightImage(ctx context.Context, client *gobizfly.Client, id string) ([]string, error) {
+	osImages, err := client.CloudServer.OSImages().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, image := range osImages {
+		for _, version := range image.Version {
+			if version.ID == id {
+				return nil, nil
+			}
+			if strings.EqualFold(version.Name, id) {
+				return []string{fmt.Sprintf("image %s is a name, did you mean its ID %s?", id, version.ID)}, nil
+			}
+			ids = append(ids, version.ID)
+		}
+	}
+	customImages, err := client.CloudServer.CustomImages().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, image := range customImages {
+		if image.ID == id {
+			return nil, nil
+		}
+		if strings.EqualFold(image.Name, id) {
+			return []string{fmt.Sprintf("image %s is a name, did you mean its ID %s?", id, image.ID)}, nil
+		}
+		ids = append(ids, image.ID)
+	}
+	return []string{fmt.Sprintf("image %s not found%s. Use 'bizfly image list' to get a list of images", id,
+		didYouMean(id, ids))}, nil
+}
+
+// preflightVolumeTypes checks the availability zone and the volume types of the root disk and the data disks
+func preflightVolumeTypes(ctx context.Context, client *gobizfly.Client, scr *gobizfly.ServerCreateRequest) ([]string, error) {
+	volumeTypes, err := client.CloudServer.Volumes().ListVolumeTypes(ctx, &gobizfly.ListVolumeTypesOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return checkVolumeTypes(volumeTypes, scr), nil
+}
+
+// checkVolumeTypes checks the disks of the create server request against the volume types. A disk with a volume type
+// needs that volume type in the zone, a disk with a type (HDD or SSD) needs a volume type of that type and of the
+// category of the server in the zone.
+func checkVolumeTypes(volumeTypes []*gobizfly.VolumeType, scr *gobizfly.ServerCreateRequest) []string {
+	var zones, names []string
+	for _, vt := range volumeTypes {
+		names = append(names, vt.Name)
+		for _, zone := range vt.AvailabilityZones {
+			if _, ok := SliceContains(zones, zone); !ok {
+				zones = append(zones, zone)
+			}
+		}
+	}
+	var problems []string
+	if _, ok := SliceContains(zones, scr.AvailabilityZone); !ok && len(zones) > 0 {
+		problems = append(problems, fmt.Sprintf("availability zone %s is invalid%s", scr.AvailabilityZone,
+			didYouMean(scr.AvailabilityZone, zones)))
+		return problems
+	}
+	checkName := func(disk string, volumeType string) {
+		for _, vt := range volumeTypes {
+			if vt.Name != volumeType {
+				continue
+			}
+			if _, ok := SliceContains(vt.AvailabilityZones, scr.AvailabilityZone); !ok {
+				problems = append(problems, fmt.Sprintf("volume type %s of %s is not available in zone %s, it is available in: %s",
+					volumeType, disk, scr.AvailabilityZone, strings.Join(vt.AvailabilityZones, ", ")))
+			}
+			return
+		}
+		problems = append(problems, fmt.Sprintf("volume type %s of %s not found%s. Use 'bizfly volume list-types' to get a list of volume types",
+			volumeType, disk, didYouMean(volumeType, names)))
+	}
+	checkType := func(disk string, diskType string) {
+		var typeZones []string
+		for _, vt := range volumeTypes {
+			if !strings.EqualFold(vt.Type, diskType) || !strings.EqualFold(vt.Category, scr.Type) {
+				continue
+			}
+			if _, ok := SliceContains(vt.AvailabilityZones, scr.AvailabilityZone); ok {
+				return
+			}
+			for _, zone := range vt.AvailabilityZones {
+				if _, ok := SliceContains(typeZones, zone); !ok {
+					typeZones = append(typeZones, zone)
+				}
+			}
+		}
+		if len(typeZones) == 0 {
+			problems = append(problems, fmt.Sprintf("%s type %s is not available in category %s. Use 'bizfly volume list-types' to get a list of volume types",
+				disk, diskType, scr.Type))
+			return
+		}
+		sort.Strings(typeZones)
+		problems = append(problems, fmt.Sprintf("%s type %s in category %s is not available in zone %s, it is available in: %s",
+			disk, diskType, scr.Type, scr.AvailabilityZone, strings.Join(typeZones, ", ")))
+	}
+	check := func(disk string, d *gobizfly.ServerDisk) {
+		switch {
+		case d.VolumeType != nil:
+			checkName(disk, *d.VolumeType)
+		case d.Type != nil:
+			checkType(disk, *d.Type)
+		}
+	}
+	check("root disk", scr.RootDisk)
+	for i, disk := range scr.DataDisks {
+		check(fmt.Sprintf("data disk %d", i+1), disk)
+	}
+	return problems
+}
+
+func shortFlavorName(name string) string {
+	return shortFlavorNameRegexp.FindString(name)
+}
+
+// didYouMean returns a suggestion of the candidate closest to the value, or an empty string
+// if no candidate is close enough
+func didYouMean(value string, candidates []string) string {
+	best, bestDistance := "", -1
+	for _, candidate := range candidates {
+		d := levenshtein(strings.ToLower(value), strings.ToLower(candidate))
+		if bestDistance == -1 || d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	maxDistance := len(value) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	if best == "" || bestDistance > maxDistance {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %s?)", best)
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func init() {
+	serverCreateCmd.PersistentFlags().BoolVar(&skipPreflight, "skip-preflight", false,
+		"Skip checking the flavor, image, volume types and zone before creating the server")
+}
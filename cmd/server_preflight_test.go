@@ -0,0 +1,64 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"testing"
+
+	"github.com/bizflycloud/gobizfly"
+)
+
+func TestCheckVolumeTypes(t *testing.T) {
+	volumeTypes := []*gobizfly.VolumeType{
+		{Name: "PREMIUM-HDD1", Category: "premium", Type: "HDD", AvailabilityZones: []string{"HN1", "HN2"}},
+		{Name: "PREMIUM-SSD1", Category: "premium", Type: "SSD", AvailabilityZones: []string{"HN1"}},
+		{Name: "BASIC-HDD1", Category: "basic", Type: "HDD", AvailabilityZones: []string{"HN1"}},
+	}
+	disk := func(diskType, volumeType string) *gobizfly.ServerDisk {
+		d := &gobizfly.ServerDisk{Size: 40}
+		if diskType != "" {
+			d.Type = &diskType
+		}
+		if volumeType != "" {
+			d.VolumeType = &volumeType
+		}
+		return d
+	}
+	tests := []struct {
+		name      string
+		category  string
+		zone      string
+		rootDisk  *gobizfly.ServerDisk
+		dataDisks []*gobizfly.ServerDisk
+		problems  int
+	}{
+		{name: "root disk type", category: "premium", zone: "HN1", rootDisk: disk("SSD", ""), problems: 0},
+		{name: "root disk type in other zone", category: "premium", zone: "HN2", rootDisk: disk("SSD", ""), problems: 1},
+		{name: "root disk type in other category", category: "basic", zone: "HN1", rootDisk: disk("SSD", ""), problems: 1},
+		{name: "root disk volume type", category: "basic", zone: "HN2", rootDisk: disk("", "PREMIUM-HDD1"), problems: 0},
+		{name: "unknown volume type", category: "premium", zone: "HN1", rootDisk: disk("", "PREMIUM-NVME"), problems: 1},
+		{name: "data disk type", category: "premium", zone: "HN2", rootDisk: disk("HDD", ""),
+			dataDisks: []*gobizfly.ServerDisk{disk("HDD", ""), disk("SSD", "")}, problems: 1},
+		{name: "invalid zone", category: "premium", zone: "HCM9", rootDisk: disk("HDD", ""), problems: 1},
+	}
+	for _, tt := range tests {
+		scr := &gobizfly.ServerCreateRequest{Type: tt.category, AvailabilityZone: tt.zone, RootDisk: tt.rootDisk,
+			DataDisks: tt.dataDisks}
+		if got := checkVolumeTypes(volumeTypes, scr); len(got) != tt.problems {
+			t.Errorf("%s: checkVolumeTypes() = %v, want %d problems", tt.name, got, tt.problems)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rebuildImageID      string
+	rebuildYes          bool
+	serverRebuildHeader = []string{"ID", "Name", "Status", "Image Before", "Image After", "WAN IP", "Data Volumes"}
+)
+
+// serverRebuildCmd represents the server rebuild command
+var serverRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Rebuild a server with a new OS image",
+	Long: `Reinstall the OS of a server from an image. The data on the root disk is lost,
+the WAN IP, network interfaces and data volumes of the server are kept.
+Example: bizfly server rebuild <server-id> --image-id <image-id>
+Example: bizfly server rebuild <server-id> --image-id <image-id> --yes --wait
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify server-id in the command. Use bizfly server rebuild <server-id> --image-id <image-id>")
+			os.Exit(1)
+		}
+		if len(args) > 1 {
+			fmt.Printf("Unknow variable %s", strings.Join(args[1:], ""))
+		}
+		client, ctx := getApiClient(cmd)
+		before, err := client.CloudServer.Get(ctx, args[0])
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Server %s not found.\n", args[0])
+				os.Exit(1)
+			}
+			log.Fatal(err)
+		}
+		imageBefore := serverImage(ctx, client, before)
+		imageAfter := rebuildImageID
+		if name := osImageName(ctx, client, rebuildImageID); name != "" {
+			imageAfter = fmt.Sprintf("%s (%s)", name, rebuildImageID)
+		}
+		fmt.Printf("Server %s (%s) will be rebuilt\n", before.Name, before.ID)
+		fmt.Printf("  Current image: %s\n", imageBefore)
+		fmt.Printf("  New image:     %s\n", imageAfter)
+		fmt.Println("All data on the root disk will be lost. The WAN IP, network interfaces and data volumes are kept.")
+		if !rebuildYes && !newWizard(os.Stdin).confirm("Rebuild the server?") {
+			fmt.Println("Server is not rebuilt")
+			return
+		}
+
+		task, err := client.CloudServer.Rebuild(ctx, before.ID, rebuildImageID)
+		if err != nil {
+			fmt.Printf("Rebuild server %s error: %v\n", before.ID, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Rebuilding server %s with task id: %s\n", before.ID, task.TaskID)
+		if !serverCreateWait {
+			return
+		}
+		result := &serverCreateResult{Name: before.Name, ServerID: before.ID, TaskID: task.TaskID}
+		waitServerActive(ctx, client, result)
+		if result.Err != nil {
+			fmt.Printf("Rebuild server %s error: %v\n", before.ID, result.Err)
+			os.Exit(1)
+		}
+		after, err := client.CloudServer.Get(ctx, before.ID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		wanBefore, wanAfter := serverWanIPs(before), serverWanIPs(after)
+		volumesBefore, volumesAfter := serverDataVolumeIDs(before), serverDataVolumeIDs(after)
+		if wanBefore != wanAfter {
+			fmt.Printf("Warning: WAN IP changed from %s to %s\n", wanBefore, wanAfter)
+		}
+		if volumesBefore != volumesAfter {
+			fmt.Printf("Warning: data volumes changed from %s to %s\n", volumesBefore, volumesAfter)
+		}
+		formatter.Output(serverRebuildHeader, [][]string{{after.ID, after.Name, after.Status, imageBefore,
+			serverImage(ctx, client, after), wanAfter, volumesAfter}})
+	},
+}
+
+// serverImage returns the name and ID of the image of the root disk of a server
+func serverImage(ctx context.Context, client *gobizfly.Client, server *gobizfly.Server) string {
+	for _, volume := range server.AttachedVolumes {
+		if volume.AttachedType != attachTypeRootDisk {
+			continue
+		}
+		rootDisk, err := client.CloudServer.Volumes().Get(ctx, volume.ID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		image := rootDisk.ImageMetadata
+		if image.ImageID == "" {
+			return "unknown"
+		}
+		return fmt.Sprintf("%s (%s)", image.ImageName, image.ImageID)
+	}
+	return "unknown"
+}
+
+// osImageName returns the name of an OS image, or an empty string if it is not an OS image
+func osImageName(ctx context.Context, client *gobizfly.Client, id string) string {
+	images, err := client.CloudServer.OSImages().List(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, image := range images {
+		for _, version := range image.Version {
+			if version.ID == id {
+				return fmt.Sprintf("%s %s", image.OSDistribution, version.Name)
+			}
+		}
+	}
+	return ""
+}
+
+func serverWanIPs(server *gobizfly.Server) string {
+	var ips []string
+	for _, ip := range server.IPAddresses.WanV4Addresses {
+		ips = append(ips, ip.Address)
+	}
+	sort.Strings(ips)
+	return strings.Join(ips, ", ")
+}
+
+func serverDataVolumeIDs(server *gobizfly.Server) string {
+	var ids []string
+	for _, volume := range server.AttachedVolumes {
+		if volume.AttachedType != attachTypeRootDisk {
+			ids = append(ids, volume.ID)
+		}
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ", ")
+}
+
+func init() {
+	serverCmd.AddCommand(serverRebuildCmd)
+	srbpf := serverRebuildCmd.PersistentFlags()
+	srbpf.StringVar(&rebuildImageID, "image-id", "", "ID of the OS image to install")
+	_ = cobra.MarkFlagRequired(srbpf, "image-id")
+	srbpf.BoolVarP(&rebuildYes, "yes", "y", false, "Rebuild without confirmation")
+	srbpf.BoolVar(&serverCreateWait, "wait", false, "Wait until the server is ACTIVE")
+	srbpf.DurationVar(&serverWaitTimeout, "wait-timeout", 15*time.Minute, "Maximum time to wait for the server")
+}
@@ -0,0 +1,180 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bizflycloud/gobizfly"
+)
+
+var (
+	resizeSafe     bool
+	resizeStop     bool
+	resizeCategory string
+)
+
+// safeResizeServer checks the flavor, stops the server, resizes it, waits for the new flavor and starts the
+// server again if it was running. When a step fails the server is started again and the failed step is reported.
+// The flavor is looked up in --category, the category of the server by default.
+func safeResizeServer(ctx context.Context, client *gobizfly.Client, serverID, flavor string) error {
+	server, err := client.CloudServer.Get(ctx, serverID)
+	if err != nil {
+		if errors.Is(err, gobizfly.ErrNotFound) {
+			return fmt.Errorf("server %s not found", serverID)
+		}
+		return err
+	}
+	category := resizeCategory
+	if category == "" {
+		category = server.Category
+	}
+	if sameFlavorInCategory(server.FlavorName, server.Category, flavor, category) {
+		return fmt.Errorf("server %s already has flavor %s in category %s", serverID, server.FlavorName, server.Category)
+	}
+	flavor, problems, err := resolveFlavor(ctx, client, flavor, category)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return errors.New(problems[0])
+	}
+
+	wasRunning := server.Status == serverStatusActive
+	if wasRunning && resizeStop {
+		fmt.Printf("Stopping server %s\n", serverID)
+		if _, err := client.CloudServer.Stop(ctx, serverID); err != nil {
+			return fmt.Errorf("stop server: %v", err)
+		}
+		if _, err := waitServerStatus(ctx, client, serverID, serverStatusShutoff); err != nil {
+			return fmt.Errorf("stop server: %v. %s", err, restartServer(ctx, client, serverID, server.FlavorName))
+		}
+	}
+
+	fmt.Printf("Resizing server %s from %s to %s\n", serverID, server.FlavorName, flavor)
+	if _, err := client.CloudServer.Resize(ctx, serverID, flavor); err != nil {
+		return fmt.Errorf("resize server: %v. %s", err, restartServerIf(ctx, client, serverID, server.FlavorName,
+			wasRunning && resizeStop))
+	}
+	resized, err := waitServerFlavor(ctx, client, serverID, flavor, category)
+	if err != nil {
+		return fmt.Errorf("resize server: %v. %s", err, restartServerIf(ctx, client, serverID, server.FlavorName,
+			wasRunning && resizeStop))
+	}
+	fmt.Printf("Server %s has flavor %s\n", serverID, resized.FlavorName)
+
+	if wasRunning && resized.Status != serverStatusActive {
+		fmt.Printf("Starting server %s\n", serverID)
+		if _, err := client.CloudServer.Start(ctx, serverID); err != nil {
+			return fmt.Errorf("start server after resize: %v", err)
+		}
+		if _, err := waitServerStatus(ctx, client, serverID, serverStatusActive); err != nil {
+			return fmt.Errorf("start server after resize: %v", err)
+		}
+	}
+	fmt.Printf("Resized server %s to %s\n", serverID, flavor)
+	return nil
+}
+
+// restartServerIf starts the server again when it was stopped for the resize and returns the result as a message
+func restartServerIf(ctx context.Context, client *gobizfly.Client, serverID, previousFlavor string, stopped bool) string {
+	if !stopped {
+		return "The server was not stopped"
+	}
+	return restartServer(ctx, client, serverID, previousFlavor)
+}
+
+// restartServer starts the server, waits until it is ACTIVE and reports the flavor it has
+func restartServer(ctx context.Context, client *gobizfly.Client, serverID, previousFlavor string) string {
+	if _, err := client.CloudServer.Start(ctx, serverID); err != nil {
+		return fmt.Sprintf("Starting the server again failed: %v", err)
+	}
+	server, err := waitServerStatus(ctx, client, serverID, serverStatusActive)
+	if err != nil {
+		return fmt.Sprintf("The server is started again but it is not ACTIVE: %v", err)
+	}
+	if server.FlavorName != previousFlavor {
+		return fmt.Sprintf("The server is started again with flavor %s, its previous flavor was %s", server.FlavorName,
+			previousFlavor)
+	}
+	return fmt.Sprintf("The server is started again with its previous flavor %s", server.FlavorName)
+}
+
+// waitServerStatus waits until the server has the status. The server in ERROR status is an error.
+func waitServerStatus(ctx context.Context, client *gobizfly.Client, serverID, status string) (*gobizfly.Server, error) {
+	deadline := time.Now().Add(serverWaitTimeout)
+	for {
+		server, err := client.CloudServer.Get(ctx, serverID)
+		if err == nil {
+			if server.Status == status {
+				return server, nil
+			}
+			if server.Status == serverStatusError {
+				return nil, fmt.Errorf("server %s is in ERROR status", serverID)
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for server %s to be %s", serverID, status)
+		}
+		time.Sleep(serverPollInterval)
+	}
+}
+
+// waitServerFlavor waits until the server reports the flavor
+func waitServerFlavor(ctx context.Context, client *gobizfly.Client, serverID, flavor, category string) (*gobizfly.Server, error) {
+	deadline := time.Now().Add(serverWaitTimeout)
+	for {
+		server, err := client.CloudServer.Get(ctx, serverID)
+		if err == nil {
+			if sameFlavorInCategory(server.FlavorName, server.Category, flavor, category) {
+				return server, nil
+			}
+			if server.Status == serverStatusError {
+				return nil, fmt.Errorf("server %s is in ERROR status", serverID)
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for server %s to have flavor %s", serverID, flavor)
+		}
+		time.Sleep(serverPollInterval)
+	}
+}
+
+// sameFlavor compares flavors by their full names. A short name such as 2c_4g matches the flavors of the same size.
+func sameFlavor(a, b string) bool {
+	if a == b {
+		return true
+	}
+	short := shortFlavorName(a)
+	return short != "" && short == shortFlavorName(b) && (a == short || b == short)
+}
+
+// sameFlavorInCategory compares flavors like sameFlavor and also compares their categories
+func sameFlavorInCategory(a, aCategory, b, bCategory string) bool {
+	return strings.EqualFold(aCategory, bCategory) && sameFlavor(a, b)
+}
+
+func init() {
+	srpf := serverResizeCmd.PersistentFlags()
+	srpf.BoolVar(&resizeSafe, "safe", false, "Check the flavor, stop the server, resize, wait for the new flavor and start the server again")
+	srpf.BoolVar(&resizeStop, "stop", true, "Stop the running server before resizing, used with --safe")
+	srpf.StringVar(&resizeCategory, "category", "", "Category of the new flavor: basic, premium or enterprise. Default is the category of the server, used with --safe")
+	srpf.DurationVar(&serverWaitTimeout, "wait-timeout", 15*time.Minute, "Maximum time to wait for each step, used with --safe")
+}
@@ -0,0 +1,39 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import "testing"
+
+func TestSameFlavorInCategory(t *testing.T) {
+	tests := []struct {
+		a, aCategory, b, bCategory string
+		want                       bool
+	}{
+		{a: "nix.2c_4g", aCategory: "premium", b: "nix.2c_4g", bCategory: "premium", want: true},
+		{a: "nix.2c_4g", aCategory: "premium", b: "2c_4g", bCategory: "premium", want: true},
+		{a: "2c_4g", aCategory: "Premium", b: "nix.2c_4g", bCategory: "premium", want: true},
+		{a: "nix.2c_4g", aCategory: "premium", b: "2c_4g", bCategory: "basic", want: false},
+		{a: "nix.2c_4g", aCategory: "premium", b: "2c_4g_basic", bCategory: "basic", want: false},
+		{a: "nix.2c_4g", aCategory: "premium", b: "2c_4g_enterprise", bCategory: "premium", want: false},
+		{a: "nix.2c_4g", aCategory: "premium", b: "nix.4c_8g", bCategory: "premium", want: false},
+		{a: "custom", aCategory: "premium", b: "other", bCategory: "premium", want: false},
+	}
+	for _, tt := range tests {
+		if got := sameFlavorInCategory(tt.a, tt.aCategory, tt.b, tt.bCategory); got != tt.want {
+			t.Errorf("sameFlavorInCategory(%s, %s, %s, %s) = %v, want %v", tt.a, tt.aCategory, tt.b, tt.bCategory, got, tt.want)
+		}
+	}
+}
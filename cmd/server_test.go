@@ -0,0 +1,284 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bizflycloud/gobizfly"
+)
+
+func serverIDs(servers []*gobizfly.Server) []string {
+	var ids []string
+	for _, server := range servers {
+		ids = append(ids, server.ID)
+	}
+	return ids
+}
+
+func TestNameMatcher(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+		plain   bool
+	}{
+		{pattern: "", name: "web-1", want: true, plain: true},
+		{pattern: "web-1", name: "web-1", want: true, plain: true},
+		{pattern: "web-1", name: "web-10", want: false, plain: true},
+		{pattern: "web", name: "old-web-1", want: false, plain: true},
+		{pattern: "web-*", name: "web-10", want: true},
+		{pattern: "web-*", name: "db-1", want: false},
+		{pattern: "web-?", name: "web-10", want: false},
+		{pattern: "web-[12]", name: "web-2", want: true},
+		{pattern: "/^web-[0-9]+$/", name: "web-10", want: true},
+		{pattern: "/^web-[0-9]+$/", name: "web-a", want: false},
+		{pattern: "/web-[0-9]{2,3}/", name: "web-123", want: true},
+	}
+	for _, tt := range tests {
+		m, err := newNameMatcher(tt.pattern)
+		if err != nil {
+			t.Errorf("newNameMatcher(%q) error: %v", tt.pattern, err)
+			continue
+		}
+		if got := m.Match(tt.name); got != tt.want {
+			t.Errorf("newNameMatcher(%q).Match(%q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+		if m.regex == nil && m.plain != tt.plain {
+			t.Errorf("newNameMatcher(%q).plain = %v, want %v", tt.pattern, m.plain, tt.plain)
+		}
+	}
+	for _, pattern := range []string{"web-[", "/web-(/"} {
+		if _, err := newNameMatcher(pattern); err == nil {
+			t.Errorf("newNameMatcher(%q) expected an error", pattern)
+		}
+	}
+}
+
+func TestFilterServers(t *testing.T) {
+	servers := []*gobizfly.Server{
+		{ID: "1", Name: "web-1", Status: "ACTIVE", AvailabilityZone: "HN1", FlavorName: "2c_4g", Category: "premium"},
+		{ID: "2", Name: "web-2", Status: "SHUTOFF", AvailabilityZone: "HN2", FlavorName: "2c_4g", Category: "basic"},
+		{ID: "3", Name: "db-1", Status: "ACTIVE", AvailabilityZone: "HN1", FlavorName: "4c_8g", Category: "premium"},
+	}
+	webs, _ := newNameMatcher("web-*")
+	tests := []struct {
+		name   string
+		filter serverFilter
+		want   []string
+	}{
+		{name: "no filter", want: []string{"1", "2", "3"}},
+		{name: "status", filter: serverFilter{Status: "active"}, want: []string{"1", "3"}},
+		{name: "zone", filter: serverFilter{Zone: "hn2"}, want: []string{"2"}},
+		{name: "flavor", filter: serverFilter{Flavor: "4c_8g"}, want: []string{"3"}},
+		{name: "category", filter: serverFilter{Category: "PREMIUM"}, want: []string{"1", "3"}},
+		{name: "name and status", filter: serverFilter{Status: "ACTIVE", Names: webs}, want: []string{"1"}},
+		{name: "no match", filter: serverFilter{Zone: "HCM1"}},
+	}
+	for _, tt := range tests {
+		if got := serverIDs(filterServers(servers, &tt.filter)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: filterServers = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSortServers(t *testing.T) {
+	tests := []struct {
+		sortBy  string
+		reverse bool
+		want    []string
+	}{
+		{sortBy: "", want: []string{"1", "2", "3"}},
+		{sortBy: "name", want: []string{"3", "2", "1"}},
+		{sortBy: "name", reverse: true, want: []string{"1", "2", "3"}},
+		{sortBy: "status", want: []string{"1", "3", "2"}},
+		{sortBy: "created", want: []string{"2", "3", "1"}},
+		{sortBy: "created", reverse: true, want: []string{"1", "3", "2"}},
+	}
+	for _, tt := range tests {
+		servers := []*gobizfly.Server{
+			{ID: "1", Name: "web-2", Status: "ACTIVE", CreatedAt: "2022-03-01T00:00:00"},
+			{ID: "2", Name: "Web-1", Status: "SHUTOFF", CreatedAt: "2022-01-01T00:00:00"},
+			{ID: "3", Name: "db-1", Status: "ACTIVE", CreatedAt: "2022-02-01T00:00:00"},
+		}
+		sortServers(servers, tt.sortBy, tt.reverse)
+		if got := serverIDs(servers); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sortServers(%q, %v) = %v, want %v", tt.sortBy, tt.reverse, got, tt.want)
+		}
+	}
+}
+
+func TestPaginateServers(t *testing.T) {
+	var servers []*gobizfly.Server
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		servers = append(servers, &gobizfly.Server{ID: id})
+	}
+	tests := []struct {
+		limit int
+		page  int
+		want  []string
+	}{
+		{limit: 0, page: 1, want: []string{"1", "2", "3", "4", "5"}},
+		{limit: 2, page: 1, want: []string{"1", "2"}},
+		{limit: 2, page: 3, want: []string{"5"}},
+		{limit: 2, page: 4},
+		{limit: 10, page: 1, want: []string{"1", "2", "3", "4", "5"}},
+	}
+	for _, tt := range tests {
+		if got := serverIDs(paginateServers(servers, tt.limit, tt.page)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("paginateServers(%d, %d) = %v, want %v", tt.limit, tt.page, got, tt.want)
+		}
+	}
+}
+
+func TestParseDataDisk(t *testing.T) {
+	tests := []struct {
+		spec       string
+		size       int
+		diskType   string
+		volumeType string
+		wantErr    bool
+	}{
+		{spec: "size=50", size: 50, diskType: "HDD"},
+		{spec: "size=100,type=ssd", size: 100, diskType: "SSD"},
+		{spec: "size=20,volume-type=PREMIUM-SSD1", size: 20, volumeType: "PREMIUM-SSD1"},
+		{spec: "size=20,type=HDD,volume-type=BASIC_HDD1", size: 20, diskType: "HDD", volumeType: "BASIC_HDD1"},
+		{spec: "type=SSD", wantErr: true},
+		{spec: "size=0", wantErr: true},
+		{spec: "size=ten", wantErr: true},
+		{spec: "size=20,type=NVME", wantErr: true},
+		{spec: "size=20,zone=HN1", wantErr: true},
+		{spec: "20", wantErr: true},
+	}
+	for _, tt := range tests {
+		disk, err := parseDataDisk(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseDataDisk(%q) expected an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDataDisk(%q) error: %v", tt.spec, err)
+			continue
+		}
+		diskType, volumeType := "", ""
+		if disk.Type != nil {
+			diskType = *disk.Type
+		}
+		if disk.VolumeType != nil {
+			volumeType = *disk.VolumeType
+		}
+		if disk.Size != tt.size || diskType != tt.diskType || volumeType != tt.volumeType {
+			t.Errorf("parseDataDisk(%q) = %d %q %q", tt.spec, disk.Size, diskType, volumeType)
+		}
+	}
+}
+
+func TestAddAuthorizedKeys(t *testing.T) {
+	keys := []string{"ssh-ed25519 AAAA one", "ssh-ed25519 BBBB two"}
+	tests := []struct {
+		name     string
+		userData string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "empty",
+			want: "#cloud-config\nssh_authorized_keys:\n  - ssh-ed25519 AAAA one\n  - ssh-ed25519 BBBB two\n",
+		},
+		{
+			name:     "without keys",
+			userData: "#cloud-config\n# install nginx\npackages:\n  - nginx",
+			want: "#cloud-config\n# install nginx\npackages:\n  - nginx\nssh_authorized_keys:\n" +
+				"  - ssh-ed25519 AAAA one\n  - ssh-ed25519 BBBB two\n",
+		},
+		{
+			name:     "with keys",
+			userData: "#cloud-config\nssh_authorized_keys: # admins\n    - ssh-rsa CCCC admin\npackages: [nginx]\n",
+			want: "#cloud-config\nssh_authorized_keys: # admins\n    - ssh-ed25519 AAAA one\n" +
+				"    - ssh-ed25519 BBBB two\n    - ssh-rsa CCCC admin\npackages: [nginx]\n",
+		},
+		{
+			name:     "flow list",
+			userData: "#cloud-config\nssh_authorized_keys: [ssh-rsa CCCC admin]\n",
+			wantErr:  true,
+		},
+		{
+			name:     "not a list",
+			userData: "#cloud-config\nssh_authorized_keys: ssh-rsa CCCC admin\n",
+			wantErr:  true,
+		},
+		{
+			name:     "shell script",
+			userData: "#!/bin/sh\necho hello\n",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid yaml",
+			userData: "#cloud-config\npackages: [nginx\n",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		got, err := addAuthorizedKeys([]byte(tt.userData), keys)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: addAuthorizedKeys expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: addAuthorizedKeys error: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: addAuthorizedKeys = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildUserData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user-data")
+	script := "#!/bin/sh\necho hello\n"
+	if err := os.WriteFile(path, []byte(script), 0600); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := buildUserData(path, nil)
+	if err != nil {
+		t.Fatalf("buildUserData error: %v", err)
+	}
+	if decoded, _ := base64.StdEncoding.DecodeString(encoded); string(decoded) != script {
+		t.Errorf("buildUserData = %q, want %q", decoded, script)
+	}
+	if _, err := buildUserData(path, []string{"ssh-ed25519 AAAA one"}); err == nil {
+		t.Error("buildUserData of a shell script with extra keys expected an error")
+	}
+	if _, err := buildUserData(filepath.Join(t.TempDir(), "missing"), nil); err == nil {
+		t.Error("buildUserData of a missing file expected an error")
+	}
+	large := make([]byte, maxUserDataSize)
+	if err := os.WriteFile(path, large, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buildUserData(path, nil); err == nil {
+		t.Error("buildUserData of a large file expected an error")
+	}
+}
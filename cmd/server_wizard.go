@@ -0,0 +1,353 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bizflycloud/gobizfly"
+)
+
+var serverCreateInteractive bool
+
+// wizardOption is an option of a prompt with the label shown to the user and the value of the flag
+type wizardOption struct {
+	Label string
+	Value string
+}
+
+// wizard asks questions on the terminal
+type wizard struct {
+	reader *bufio.Reader
+	// cancelled is printed when the input ends before a question is answered
+	cancelled string
+}
+
+func newWizard(r io.Reader) *wizard {
+	return &wizard{reader: bufio.NewReader(r), cancelled: "Cancelled"}
+}
+
+func (w *wizard) readLine() (string, error) {
+	line, err := w.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// mustReadLine reads an answer and exits when the input ends
+func (w *wizard) mustReadLine() string {
+	line, err := w.readLine()
+	if err != nil {
+		fmt.Println()
+		fmt.Println(w.cancelled)
+		os.Exit(1)
+	}
+	return line
+}
+
+// ask asks for a value. An empty answer returns the default value.
+func (w *wizard) ask(label, defaultValue string) string {
+	for {
+		if defaultValue != "" {
+			fmt.Printf("%s [%s]: ", label, defaultValue)
+		} else {
+			fmt.Printf("%s: ", label)
+		}
+		answer := w.mustReadLine()
+		if answer == "" {
+			answer = defaultValue
+		}
+		if answer != "" {
+			return answer
+		}
+		fmt.Println("A value is required")
+	}
+}
+
+// askInt asks for a number which is at least min
+func (w *wizard) askInt(label string, defaultValue, min int) int {
+	for {
+		answer := w.ask(label, strconv.Itoa(defaultValue))
+		value, err := strconv.Atoi(answer)
+		if err == nil && value >= min {
+			return value
+		}
+		fmt.Printf("Enter a number greater than or equal to %d\n", min)
+	}
+}
+
+// choose asks to choose one option by its number or its value
+func (w *wizard) choose(label string, options []wizardOption, defaultValue string) string {
+	if len(options) == 0 {
+		log.Fatalf("No %s is available", strings.ToLower(label))
+	}
+	fmt.Println(label + ":")
+	defaultIndex := ""
+	for i, option := range options {
+		fmt.Printf("  %d) %s\n", i+1, option.Label)
+		if option.Value == defaultValue {
+			defaultIndex = strconv.Itoa(i + 1)
+		}
+	}
+	for {
+		answer := w.ask("Choose a number", defaultIndex)
+		if option, ok := findWizardOption(options, answer); ok {
+			return option.Value
+		}
+		fmt.Printf("Invalid choice %s\n", answer)
+	}
+}
+
+// chooseMany asks to choose any number of options as a comma separated list of numbers. An empty answer chooses none.
+func (w *wizard) chooseMany(label string, options []wizardOption) []string {
+	if len(options) == 0 {
+		return nil
+	}
+	fmt.Println(label + ":")
+	for i, option := range options {
+		fmt.Printf("  %d) %s\n", i+1, option.Label)
+	}
+	for {
+		fmt.Print("Choose numbers separated by commas, or leave empty for none: ")
+		answer := w.mustReadLine()
+		if answer == "" {
+			return nil
+		}
+		var values []string
+		valid := true
+		for _, part := range strings.Split(answer, ",") {
+			option, ok := findWizardOption(options, strings.TrimSpace(part))
+			if !ok {
+				fmt.Printf("Invalid choice %s\n", strings.TrimSpace(part))
+				valid = false
+				break
+			}
+			values = append(values, option.Value)
+		}
+		if valid {
+			return values
+		}
+	}
+}
+
+// confirm asks a yes/no question, the default answer is no. The end of the input answers no.
+func (w *wizard) confirm(label string) bool {
+	fmt.Printf("%s [y/N]: ", label)
+	answer, err := w.readLine()
+	if err != nil {
+		fmt.Println()
+		return false
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+func findWizardOption(options []wizardOption, answer string) (wizardOption, bool) {
+	if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(options) {
+		return options[i-1], true
+	}
+	for _, option := range options {
+		if option.Value == answer {
+			return option, true
+		}
+	}
+	return wizardOption{}, false
+}
+
+func stringOptions(values ...string) []wizardOption {
+	options := make([]wizardOption, 0, len(values))
+	for _, value := range values {
+		options = append(options, wizardOption{Label: value, Value: value})
+	}
+	return options
+}
+
+// runServerCreateWizard asks for the server create flags and sets them.
+// It returns false if the user does not confirm the creation.
+func runServerCreateWizard(ctx context.Context, client *gobizfly.Client) bool {
+	w := newWizard(os.Stdin)
+	w.cancelled = "Server create is cancelled"
+
+	serverName = w.ask("Server name", serverName)
+
+	volumeTypes, err := client.CloudServer.Volumes().ListVolumeTypes(ctx, &gobizfly.ListVolumeTypesOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	var zones []string
+	for _, vt := range volumeTypes {
+		for _, zone := range vt.AvailabilityZones {
+			if _, ok := SliceContains(zones, zone); !ok {
+				zones = append(zones, zone)
+			}
+		}
+	}
+	sort.Strings(zones)
+	availabilityZone = w.choose("Availability zone", stringOptions(zones...), availabilityZone)
+	serverCategory = w.choose("Category", stringOptions(serverCategories...), serverCategory)
+
+	flavors, err := client.CloudServer.Flavors().List(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var flavorOptions []wizardOption
+	for _, flavor := range flavors {
+		if flavor.Category != serverCategory {
+			continue
+		}
+		flavorOptions = append(flavorOptions, wizardOption{
+			Label: fmt.Sprintf("%s (%d vCPU, %d GB RAM)", flavor.Name, flavor.VCPUs, flavor.RAM/1024),
+			Value: flavor.Name,
+		})
+	}
+	flavorName = w.choose("Flavor", flavorOptions, flavorName)
+
+	osImages, err := client.CloudServer.OSImages().List(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var imageOptions []wizardOption
+	for _, image := range osImages {
+		for _, version := range image.Version {
+			imageOptions = append(imageOptions, wizardOption{
+				Label: fmt.Sprintf("%s %s", image.OSDistribution, version.Name),
+				Value: version.ID,
+			})
+		}
+	}
+	imageID = w.choose("OS image", imageOptions, imageID)
+
+	rootDiskType = w.choose("Root disk type", stringOptions(rootDiskTypes...), rootDiskType)
+	defaultSize := rootDiskSize
+	if defaultSize < minRootDiskSize {
+		defaultSize = 40
+	}
+	rootDiskSize = w.askInt("Root disk size in GB", defaultSize, minRootDiskSize)
+
+	keys, err := client.CloudServer.SSHKeys().List(ctx, &gobizfly.ListOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	var keyOptions []wizardOption
+	for _, key := range keys {
+		keyOptions = append(keyOptions, wizardOption{
+			Label: fmt.Sprintf("%s (%s)", key.SSHKeyPair.Name, key.SSHKeyPair.FingerPrint),
+			Value: key.SSHKeyPair.Name,
+		})
+	}
+	sshKeys = w.chooseMany("SSH keys", keyOptions)
+
+	fws, err := client.CloudServer.Firewalls().List(ctx, &gobizfly.ListOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	var firewallOptions []wizardOption
+	for _, fw := range fws {
+		firewallOptions = append(firewallOptions, wizardOption{Label: fmt.Sprintf("%s (%s)", fw.Name, fw.ID), Value: fw.ID})
+	}
+	firewalls = w.chooseMany("Firewalls", firewallOptions)
+
+	vpcs, err := client.CloudServer.VPCNetworks().List(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var vpcOptions []wizardOption
+	for _, vpc := range vpcs {
+		vpcOptions = append(vpcOptions, wizardOption{Label: fmt.Sprintf("%s (%s)", vpc.Name, vpc.ID), Value: vpc.ID})
+	}
+	vpcIDs = w.chooseMany("VPC networks", vpcOptions)
+
+	if networkPlan == "" {
+		networkPlan = "free_datatransfer"
+	}
+	networkPlan = w.choose("Network plan", stringOptions("free_datatransfer", "free_bandwidth"), networkPlan)
+	billingPlan = w.choose("Billing plan", stringOptions("saving_plan", "on_demand"), billingPlan)
+
+	fmt.Println()
+	fmt.Println("Equivalent command:")
+	fmt.Println(serverCreateCommandLine())
+	fmt.Println()
+	return w.confirm("Create the server?")
+}
+
+// serverCreateCommandLine returns the non-interactive create server command for the current flags
+func serverCreateCommandLine() string {
+	args := []string{"bizfly", "server", "create",
+		"--name", serverName,
+		"--availability-zone", availabilityZone,
+		"--category", serverCategory,
+		"--flavor", flavorName,
+		"--image-id", imageID,
+		"--rootdisk-type", rootDiskType,
+		"--rootdisk-size", strconv.Itoa(rootDiskSize),
+	}
+	if rootDiskVolumeType != "" {
+		args = append(args, "--rootdisk-volume-type", rootDiskVolumeType)
+	}
+	for _, key := range sshKeys {
+		args = append(args, "--ssh-key", key)
+	}
+	for _, fw := range firewalls {
+		args = append(args, "--firewall", fw)
+	}
+	for _, vpcID := range vpcIDs {
+		args = append(args, "--vpc-ids", vpcID)
+	}
+	for _, netInterface := range networkInterfaces {
+		args = append(args, "--net-interface", netInterface)
+	}
+	if !isCreatedWan {
+		args = append(args, "--is-created-wan-ip=false")
+	}
+	args = append(args, "--network-plan", networkPlan, "--billing-plan", billingPlan)
+	for _, disk := range dataDisks {
+		args = append(args, "--data-disk", disk)
+	}
+	if userDataFile != "" {
+		args = append(args, "--user-data-file", userDataFile)
+	}
+	if serverCount > 1 {
+		args = append(args, "--count", strconv.Itoa(serverCount))
+	}
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes an argument for a POSIX shell when it contains special characters
+func shellQuote(arg string) string {
+	if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.,:/=@", r))
+	}) == -1 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func init() {
+	serverCreateCmd.PersistentFlags().BoolVar(&serverCreateInteractive, "interactive", false,
+		"Choose the server options with a guided prompt and print the equivalent command")
+}
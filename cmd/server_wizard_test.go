@@ -0,0 +1,58 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWizardConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: "y\n", want: true},
+		{input: "YES\n", want: true},
+		{input: "yes", want: true},
+		{input: "n\n", want: false},
+		{input: "\n", want: false},
+		{input: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := newWizard(strings.NewReader(tt.input)).confirm("Continue?"); got != tt.want {
+			t.Errorf("confirm(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestServerCreateCommandLine(t *testing.T) {
+	serverName, availabilityZone, serverCategory, flavorName = "web 1", "HN1", "premium", "nix.2c_4g"
+	imageID, rootDiskType, rootDiskSize, rootDiskVolumeType = "image-1", "SSD", 40, "PREMIUM-SSD1"
+	sshKeys, firewalls, vpcIDs, networkInterfaces = []string{"key1"}, nil, nil, []string{"port-1"}
+	networkPlan, billingPlan, dataDisks, userDataFile, serverCount = "free_datatransfer", "on_demand", nil, "", 1
+	isCreatedWan = false
+	defer func() {
+		rootDiskVolumeType, networkInterfaces, isCreatedWan = "", []string{}, true
+	}()
+	want := "bizfly server create --name 'web 1' --availability-zone HN1 --category premium --flavor nix.2c_4g " +
+		"--image-id image-1 --rootdisk-type SSD --rootdisk-size 40 --rootdisk-volume-type PREMIUM-SSD1 " +
+		"--ssh-key key1 --net-interface port-1 --is-created-wan-ip=false --network-plan free_datatransfer " +
+		"--billing-plan on_demand"
+	if got := serverCreateCommandLine(); got != want {
+		t.Errorf("serverCreateCommandLine() = %s, want %s", got, want)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bizflycloud/bizflyctl/formatter"
 	"github.com/bizflycloud/gobizfly"
@@ -38,6 +39,16 @@ var (
 	volumeBillingPlan    string
 	serverID             string
 	category             string
+
+	volumeListStatus   string
+	volumeListType     string
+	volumeListZone     string
+	volumeListAttached bool
+	volumeListDetached bool
+	volumeListServer   string
+	volumeListBootable string
+	volumeWait         bool
+	volumeDetachForce  bool
 )
 
 // volumeCmd represents the volume command
@@ -113,15 +124,41 @@ var volumeListCmd = &cobra.Command{
 	Short: "List all volumes in your account",
 	Long: `List all volumes in your Bizfly Cloud account
 Example: bizfly volume list
+Example: bizfly volume list --status available --type SSD --zone HN1
+Example: bizfly volume list --detached --bootable false
+Example: bizfly volume list --server fd554aac-9ab1-11ea-b09d-bbaf82f02f58
 `,
 	Run: func(cmd *cobra.Command, args []string) {
+		if volumeListAttached && volumeListDetached {
+			fmt.Println("--attached and --detached cannot be used together")
+			os.Exit(1)
+		}
+		bootable, err := parseOptionalBool(volumeListBootable)
+		if err != nil {
+			fmt.Printf("Invalid --bootable %s. Use true or false\n", volumeListBootable)
+			os.Exit(1)
+		}
 		client, ctx := getApiClient(cmd)
-		volumes, err := client.CloudServer.Volumes().List(ctx, &gobizfly.VolumeListOptions{})
+		volumes, err := client.CloudServer.Volumes().List(ctx, &gobizfly.VolumeListOptions{
+			Status:           volumeListStatus,
+			AvailabilityZone: volumeListZone,
+			Bootable:         bootable,
+		})
 		if err != nil {
 			log.Fatal(err)
 		}
+		filter := &volumeFilter{
+			Status:   volumeListStatus,
+			Type:     volumeListType,
+			Zone:     volumeListZone,
+			ServerID: volumeListServer,
+			Bootable: bootable,
+		}
+		if volumeListAttached || volumeListDetached {
+			filter.Attached = &volumeListAttached
+		}
 		var data [][]string
-		for _, volume := range volumes {
+		for _, volume := range filterVolumes(volumes, filter) {
 			serverID := ""
 			if (len(volume.Attachments)) > 0 {
 				serverID = volume.Attachments[0].ServerID
@@ -134,6 +171,53 @@ Example: bizfly volume list
 	},
 }
 
+// volumeFilter contains the filters of volumes. Filters supported by the API are applied again on the result.
+type volumeFilter struct {
+	Status   string
+	Type     string
+	Zone     string
+	ServerID string
+	Attached *bool
+	Bootable *bool
+}
+
+// filterVolumes returns the volumes matching the filter
+func filterVolumes(volumes []*gobizfly.Volume, filter *volumeFilter) []*gobizfly.Volume {
+	var result []*gobizfly.Volume
+	for _, volume := range volumes {
+		if filter.Status != "" && !strings.EqualFold(volume.Status, filter.Status) {
+			continue
+		}
+		if filter.Type != "" && !strings.EqualFold(volume.VolumeType, filter.Type) &&
+			!strings.EqualFold(volume.Type, filter.Type) {
+			continue
+		}
+		if filter.Zone != "" && !strings.EqualFold(volume.AvailabilityZone, filter.Zone) {
+			continue
+		}
+		if filter.Attached != nil && *filter.Attached != (len(volume.Attachments) > 0) {
+			continue
+		}
+		if filter.Bootable != nil && *filter.Bootable != volume.Bootable {
+			continue
+		}
+		if filter.ServerID != "" && !volumeAttachedTo(volume, filter.ServerID) {
+			continue
+		}
+		result = append(result, volume)
+	}
+	return result
+}
+
+func volumeAttachedTo(volume *gobizfly.Volume, serverID string) bool {
+	for _, attachment := range volume.Attachments {
+		if attachment.ServerID == serverID {
+			return true
+		}
+	}
+	return false
+}
+
 // volumeCreateCmd represents the create command
 var volumeCreateCmd = &cobra.Command{
 	Use:   "create",
@@ -143,6 +227,16 @@ Create a new volume
 Use: bizfly volume create
 `,
 	Run: func(cmd *cobra.Command, args []string) {
+		if estimateCost {
+			p := loadPricing()
+			line, err := p.volumeLine("Volume", volumeType, volumeSize)
+			if err != nil {
+				fmt.Printf("Estimate cost error: %v\n", err)
+				os.Exit(1)
+			}
+			p.printEstimate([]costLine{line})
+			return
+		}
 		client, ctx := getApiClient(cmd)
 		vcr := gobizfly.VolumeCreateRequest{
 			Name:             volumeName,
@@ -179,6 +273,7 @@ var volumeAttachCmd = &cobra.Command{
 	Long: `
 Attach a volume to a server
 Use: bizfly volume attach <volume-id> <server-id>
+Use: bizfly volume attach <volume-id> <server-id> --wait
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 2 {
@@ -202,6 +297,21 @@ Use: bizfly volume attach <volume-id> <server-id>
 			os.Exit(1)
 		}
 		fmt.Println(res.Message)
+		if !volumeWait {
+			return
+		}
+		volume, err := waitVolumeStatus(ctx, client, volumeID, volumeStatusInUse, volumeWaitTimeout)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		for _, attachment := range volume.Attachments {
+			if attachment.ServerID == serverID {
+				fmt.Printf("Volume %s is attached to server %s as %s\n", volumeID, serverID, attachment.Device)
+				return
+			}
+		}
+		fmt.Printf("Volume %s is attached to server %s\n", volumeID, serverID)
 	},
 }
 
@@ -210,8 +320,9 @@ var volumeDetachCmd = &cobra.Command{
 	Use:   "detach",
 	Short: "Detach a volume from a server",
 	Long: `
-Detach a volume from a server
+Detach a volume from a server. A volume attached as root disk is only detached with --force.
 Use: bizfly volume detach <volume-id> <server-id>
+Use: bizfly volume detach <volume-id> <server-id> --wait
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 2 {
@@ -229,12 +340,32 @@ Use: bizfly volume detach <volume-id> <server-id>
 			os.Exit(1)
 		}
 		client, ctx := getApiClient(cmd)
+		volume, err := client.CloudServer.Volumes().Get(ctx, volumeID)
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Volume %s not found.\n", volumeID)
+				os.Exit(1)
+			}
+			log.Fatal(err)
+		}
+		if volume.AttachedType == attachTypeRootDisk && !volumeDetachForce {
+			fmt.Printf("Volume %s is the root disk of server %s. Use --force to detach it\n", volumeID, serverID)
+			os.Exit(1)
+		}
 		res, err := client.CloudServer.Volumes().Detach(ctx, volumeID, serverID)
 		if err != nil {
 			fmt.Printf("Detach a volume from a server error: %v", err)
 			os.Exit(1)
 		}
 		fmt.Println(res.Message)
+		if !volumeWait {
+			return
+		}
+		if _, err := waitVolumeStatus(ctx, client, volumeID, volumeStatusAvailable, volumeWaitTimeout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Volume %s is detached from server %s\n", volumeID, serverID)
 	},
 }
 
@@ -253,6 +384,22 @@ Use: bizfly volume extend <volume-id> --size <new-size>
 		}
 		volumeID := args[0]
 		client, ctx := getApiClient(cmd)
+		if estimateCost {
+			p := loadPricing()
+			volume, err := client.CloudServer.Volumes().Get(ctx, volumeID)
+			if err != nil {
+				log.Fatal(err)
+			}
+			before, err := p.volumeLine("Volume", volume.VolumeType, volume.Size)
+			if err != nil {
+				fmt.Printf("Estimate cost error: %v\n", err)
+				os.Exit(1)
+			}
+			after := before
+			after.Quantity = float64(volumeSize)
+			p.printDifference([]costLine{before}, []costLine{after})
+			return
+		}
 		_, err := client.CloudServer.Volumes().ExtendVolume(ctx, volumeID, volumeSize)
 		if err != nil {
 			fmt.Printf("Extend volume error: %v\n", err)
@@ -344,6 +491,14 @@ Use: bizfly volume list-types --category <category> --availability-zone <availab
 func init() {
 	rootCmd.AddCommand(volumeCmd)
 	volumeCmd.AddCommand(volumeListCmd)
+	vlpf := volumeListCmd.PersistentFlags()
+	vlpf.StringVar(&volumeListStatus, "status", "", "Filter volumes by status, e.g. available or in-use")
+	vlpf.StringVar(&volumeListType, "type", "", "Filter volumes by volume type, e.g. SSD or HDD")
+	vlpf.StringVar(&volumeListZone, "zone", "", "Filter volumes by availability zone")
+	vlpf.BoolVar(&volumeListAttached, "attached", false, "Only list volumes attached to a server")
+	vlpf.BoolVar(&volumeListDetached, "detached", false, "Only list volumes not attached to any server")
+	vlpf.StringVar(&volumeListServer, "server", "", "Only list volumes attached to the server ID")
+	vlpf.StringVar(&volumeListBootable, "bootable", "", "Filter volumes by bootable: true or false")
 	volumeCmd.AddCommand(volumeGetCmd)
 	volumeCmd.AddCommand(volumeDeleteCmd)
 
@@ -363,14 +518,23 @@ func init() {
 	vcpf.StringVar(&snapshotID, "snapshot-id", "", "Create a volume from a snapshot")
 	vcpf.StringVar(&serverID, "server-id", "", "Create a new volume and attach to a server")
 	vcpf.StringVar(&volumeBillingPlan, "billing-plan", "saving_plan", "Billing plan of volume: saving_plan, on_demand")
+	addEstimateFlags(volumeCreateCmd)
 	volumeCmd.AddCommand(volumeCreateCmd)
 
+	vapf := volumeAttachCmd.PersistentFlags()
+	vapf.BoolVar(&volumeWait, "wait", false, "Wait until the volume is in-use and print its device")
+	vapf.DurationVar(&volumeWaitTimeout, "wait-timeout", 15*time.Minute, "Maximum time to wait, used with --wait")
 	volumeCmd.AddCommand(volumeAttachCmd)
 
+	vdpf := volumeDetachCmd.PersistentFlags()
+	vdpf.BoolVar(&volumeWait, "wait", false, "Wait until the volume is available")
+	vdpf.DurationVar(&volumeWaitTimeout, "wait-timeout", 15*time.Minute, "Maximum time to wait, used with --wait")
+	vdpf.BoolVar(&volumeDetachForce, "force", false, "Detach the volume even if it is the root disk of the server")
 	volumeCmd.AddCommand(volumeDetachCmd)
 
 	extendVolumeCmd.PersistentFlags().IntVar(&volumeSize, "size", 0, "Volume size")
 	_ = cobra.MarkFlagRequired(extendVolumeCmd.PersistentFlags(), "size")
+	addEstimateFlags(extendVolumeCmd)
 	volumeCmd.AddCommand(extendVolumeCmd)
 	pvpf := patchVolumeCmd.PersistentFlags()
 	pvpf.StringVar(&description, "description", "", "Patched volume description")
@@ -0,0 +1,151 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+	"github.com/spf13/cobra"
+)
+
+const (
+	volumeStatusInUse = "in-use"
+	volumeStatusError = "error"
+)
+
+var (
+	cloneVolumeName   string
+	cloneVolumeType   string
+	cloneAttachServer string
+	cloneKeepSnapshot bool
+	volumeWaitTimeout time.Duration
+)
+
+// volumeCloneCmd represents the volume clone command
+var volumeCloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Clone a volume",
+	Long: `Create a new volume from a snapshot of a volume, with the same size, category, zone and billing plan.
+The intermediate snapshot is deleted once the new volume is ready, unless --keep-snapshot is given.
+Example: bizfly volume clone <volume-id> --name staging-data
+Example: bizfly volume clone <volume-id> --name staging-data --type SSD --attach <server-id>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Println("You need to specify volume-id in the command. Use bizfly volume clone <volume-id> --name <name>")
+			os.Exit(1)
+		}
+		if len(args) > 1 {
+			fmt.Printf("Unknow variable %s", strings.Join(args[1:], ""))
+		}
+		client, ctx := getApiClient(cmd)
+		source, err := client.CloudServer.Volumes().Get(ctx, args[0])
+		if err != nil {
+			if errors.Is(err, gobizfly.ErrNotFound) {
+				fmt.Printf("Volume %s not found.\n", args[0])
+				os.Exit(1)
+			}
+			log.Fatal(err)
+		}
+		if cloneVolumeType == "" {
+			cloneVolumeType = source.VolumeType
+		}
+
+		snapshot := snapshotAndWait(ctx, client, source.ID, cloneVolumeName+"-clone")
+		fmt.Printf("Creating volume %s from snapshot %s\n", cloneVolumeName, snapshot.Id)
+		clone, err := client.CloudServer.Volumes().Create(ctx, &gobizfly.VolumeCreateRequest{
+			Name:             cloneVolumeName,
+			Description:      fmt.Sprintf("Clone of volume %s", source.ID),
+			Size:             source.Size,
+			VolumeType:       cloneVolumeType,
+			VolumeCategory:   source.Category,
+			AvailabilityZone: source.AvailabilityZone,
+			SnapshotID:       snapshot.Id,
+			ServerID:         cloneAttachServer,
+			BillingPlan:      source.BillingPlan,
+		})
+		if err != nil {
+			log.Fatalf("Create volume from snapshot %s error: %v. The snapshot is kept.", snapshot.Id, err)
+		}
+		status := volumeStatusAvailable
+		if cloneAttachServer != "" {
+			status = volumeStatusInUse
+		}
+		if clone, err = waitVolumeStatus(ctx, client, clone.ID, status, volumeWaitTimeout); err != nil {
+			log.Fatalf("%v. The snapshot %s is kept.", err, snapshot.Id)
+		}
+
+		serverID := ""
+		if len(clone.Attachments) > 0 {
+			serverID = clone.Attachments[0].ServerID
+		}
+		formatter.Output(volumeHeaderList, [][]string{{clone.ID, clone.Name, clone.Description, clone.Status,
+			strconv.Itoa(clone.Size), clone.CreatedAt, clone.VolumeType, clone.SnapshotID, clone.BillingPlan,
+			clone.AvailabilityZone, serverID}})
+
+		if cloneKeepSnapshot {
+			fmt.Printf("Snapshot %s is kept\n", snapshot.Id)
+			return
+		}
+		if err := client.CloudServer.Snapshots().Delete(ctx, snapshot.Id); err != nil {
+			fmt.Printf("Delete snapshot %s error: %v. Delete it with bizfly snapshot delete %s\n", snapshot.Id, err,
+				snapshot.Id)
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted snapshot %s\n", snapshot.Id)
+	},
+}
+
+// waitVolumeStatus waits until the volume has the status. The volume in error status is an error.
+func waitVolumeStatus(ctx context.Context, client *gobizfly.Client, id, status string, timeout time.Duration) (*gobizfly.Volume, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		volume, err := client.CloudServer.Volumes().Get(ctx, id)
+		if err == nil {
+			if strings.EqualFold(volume.Status, status) {
+				return volume, nil
+			}
+			if strings.HasPrefix(strings.ToLower(volume.Status), volumeStatusError) {
+				return nil, fmt.Errorf("volume %s is in %s status", id, volume.Status)
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for volume %s to be %s", id, status)
+		}
+		time.Sleep(serverPollInterval)
+	}
+}
+
+func init() {
+	volumeCmd.AddCommand(volumeCloneCmd)
+	vclpf := volumeCloneCmd.PersistentFlags()
+	vclpf.StringVar(&cloneVolumeName, "name", "", "Name of the new volume")
+	_ = cobra.MarkFlagRequired(vclpf, "name")
+	vclpf.StringVar(&cloneVolumeType, "type", "", "Volume type of the new volume. Default is the type of the source volume")
+	vclpf.StringVar(&cloneAttachServer, "attach", "", "Attach the new volume to the server ID")
+	vclpf.BoolVar(&cloneKeepSnapshot, "keep-snapshot", false, "Keep the intermediate snapshot")
+	vclpf.DurationVar(&snapshotWaitTimeout, "snapshot-timeout", 30*time.Minute, "Maximum time to wait for the snapshot")
+	vclpf.DurationVar(&volumeWaitTimeout, "wait-timeout", 15*time.Minute, "Maximum time to wait for the new volume")
+}
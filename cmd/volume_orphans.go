@@ -0,0 +1,223 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bizflycloud/bizflyctl/formatter"
+	"github.com/bizflycloud/gobizfly"
+	"github.com/spf13/cobra"
+)
+
+const volumeStatusAvailable = "available"
+
+var (
+	orphanDays         int
+	orphanDelete       bool
+	orphanYes          bool
+	volumeOrphanHeader = []string{"ID", "Name", "Size", "Type", "Zone", "Idle Days", "Since", "Last Snapshot",
+		"Monthly Waste"}
+	volumeDeleteHeader = []string{"ID", "Name", "Result", "Error"}
+)
+
+// volumeOrphan is a detached volume with its last snapshot and monthly cost. Since is the time field
+// the days are counted from: updated_at or created_at.
+type volumeOrphan struct {
+	Volume       *gobizfly.Volume
+	Days         int
+	Since        string
+	LastSnapshot *gobizfly.Snapshot
+	MonthlyCost  float64
+	HasCost      bool
+}
+
+// volumeOrphansCmd represents the volume orphans command
+var volumeOrphansCmd = &cobra.Command{
+	Use:   "orphans",
+	Short: "Report volumes detached for a long time",
+	Long: `Report the volumes which are not attached to any server for longer than --days days, with their sizes,
+last snapshot and estimated monthly cost from the pricing file. Use --delete to delete them.
+The API does not return when a volume was detached, so the days are counted from the last update of the volume
+(updated_at), or from its creation (created_at) when the update time is missing. The Since column tells which one
+is used. A volume detached long ago but renamed or resized recently is not reported, and a volume updated without
+being used may look active, so check the report before using --delete.
+Example: bizfly volume orphans --days 30
+Example: bizfly volume orphans --days 90 --delete --yes
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 0 {
+			fmt.Printf("Unknow variable %s", strings.Join(args, ""))
+		}
+		if orphanDays < 0 {
+			fmt.Println("--days must not be negative")
+			os.Exit(1)
+		}
+		client, ctx := getApiClient(cmd)
+		volumes, err := client.CloudServer.Volumes().List(ctx, &gobizfly.VolumeListOptions{Status: volumeStatusAvailable})
+		if err != nil {
+			log.Fatal(err)
+		}
+		p, err := readPricing()
+		if err != nil {
+			fmt.Printf("Monthly waste is not estimated: %v\n", err)
+		}
+		orphans, err := findVolumeOrphans(ctx, client, p, volumes, orphanDays)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(orphans) == 0 {
+			fmt.Printf("No volume is detached for more than %d days\n", orphanDays)
+			return
+		}
+		printVolumeOrphans(p, orphans)
+		if !orphanDelete {
+			return
+		}
+		if !orphanYes && !newWizard(os.Stdin).confirm(fmt.Sprintf("Delete %d volumes?", len(orphans))) {
+			fmt.Println("Nothing is deleted")
+			return
+		}
+		var data [][]string
+		failed := 0
+		for _, orphan := range orphans {
+			row := []string{orphan.Volume.ID, orphan.Volume.Name, "deleted", ""}
+			if err := client.CloudServer.Volumes().Delete(ctx, orphan.Volume.ID); err != nil {
+				row[2], row[3] = "failed", err.Error()
+				failed++
+			}
+			data = append(data, row)
+		}
+		formatter.Output(volumeDeleteHeader, data)
+		fmt.Printf("%d deleted, %d failed\n", len(orphans)-failed, failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// findVolumeOrphans returns the detached volumes which have been idle for at least the number of days,
+// the oldest first. The monthly cost is only computed when the pricing is given.
+func findVolumeOrphans(ctx context.Context, client *gobizfly.Client, p *pricing, volumes []*gobizfly.Volume,
+	days int) ([]*volumeOrphan, error) {
+	detached := false
+	var orphans []*volumeOrphan
+	for _, volume := range filterVolumes(volumes, &volumeFilter{Status: volumeStatusAvailable, Attached: &detached}) {
+		age, since, ok := volumeIdleDays(volume, time.Now())
+		if !ok || age < days {
+			continue
+		}
+		orphan := &volumeOrphan{Volume: volume, Days: age, Since: since}
+		snapshots, err := client.CloudServer.Snapshots().List(ctx, &gobizfly.ListSnasphotsOptions{VolumeId: volume.ID})
+		if err != nil {
+			return nil, err
+		}
+		for _, snapshot := range snapshots {
+			if snapshot.VolumeId == volume.ID &&
+				(orphan.LastSnapshot == nil || snapshot.CreateAt > orphan.LastSnapshot.CreateAt) {
+				orphan.LastSnapshot = snapshot
+			}
+		}
+		if p != nil {
+			orphan.MonthlyCost, orphan.HasCost = p.volumeMonthlyCost(volume)
+		}
+		orphans = append(orphans, orphan)
+	}
+	sort.SliceStable(orphans, func(i, j int) bool {
+		return orphans[i].Days > orphans[j].Days
+	})
+	return orphans, nil
+}
+
+func printVolumeOrphans(p *pricing, orphans []*volumeOrphan) {
+	var data [][]string
+	size := 0
+	var waste float64
+	for _, orphan := range orphans {
+		v := orphan.Volume
+		lastSnapshot := ""
+		if orphan.LastSnapshot != nil {
+			lastSnapshot = fmt.Sprintf("%s (%s)", orphan.LastSnapshot.Id, orphan.LastSnapshot.CreateAt)
+		}
+		cost := "-"
+		if orphan.HasCost {
+			cost = formatPrice(orphan.MonthlyCost)
+			waste += orphan.MonthlyCost
+		}
+		size += v.Size
+		data = append(data, []string{v.ID, v.Name, strconv.Itoa(v.Size), v.VolumeType, v.AvailabilityZone,
+			strconv.Itoa(orphan.Days), orphan.Since, lastSnapshot, cost})
+	}
+	formatter.Output(volumeOrphanHeader, data)
+	fmt.Printf("%d volumes, %d GB", len(orphans), size)
+	if p != nil {
+		fmt.Printf(", %s %s per month", formatPrice(waste), p.Currency)
+	}
+	fmt.Println()
+}
+
+// volumeMonthlyCost returns the monthly cost of a volume with its billing plan
+func (p *pricing) volumeMonthlyCost(volume *gobizfly.Volume) (float64, bool) {
+	line, err := p.volumeLine("Volume", volume.VolumeType, volume.Size)
+	if err != nil {
+		if line, err = p.volumeLine("Volume", volume.Type, volume.Size); err != nil {
+			return 0, false
+		}
+	}
+	hourly := line.Price.SavingPlan
+	if volume.BillingPlan == "on_demand" {
+		hourly = line.Price.OnDemand
+	}
+	return line.Quantity * hourly * p.HoursPerMonth, true
+}
+
+// volumeIdleDays returns the number of days since the volume was updated, or created when the update time is
+// missing, and the field it was computed from. The API has no detach time, the update time stands in for it.
+func volumeIdleDays(volume *gobizfly.Volume, now time.Time) (int, string, bool) {
+	if t, ok := parseVolumeTime(volume.UpdatedAt); ok {
+		return int(now.Sub(t).Hours() / 24), "updated_at", true
+	}
+	if t, ok := parseVolumeTime(volume.CreatedAt); ok {
+		return int(now.Sub(t).Hours() / 24), "created_at", true
+	}
+	return 0, "", false
+}
+
+// parseVolumeTime parses the created_at and updated_at times of volumes
+func parseVolumeTime(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05.999999", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func init() {
+	volumeCmd.AddCommand(volumeOrphansCmd)
+	vopf := volumeOrphansCmd.PersistentFlags()
+	vopf.IntVar(&orphanDays, "days", 30, "Minimum number of days since the volume was last updated, which stands in for the detach time")
+	vopf.BoolVar(&orphanDelete, "delete", false, "Delete the reported volumes")
+	vopf.BoolVarP(&orphanYes, "yes", "y", false, "Delete without confirmation, used with --delete")
+	addPricingFlags(volumeOrphansCmd)
+}
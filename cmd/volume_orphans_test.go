@@ -0,0 +1,69 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly"
+)
+
+func TestVolumeIdleDays(t *testing.T) {
+	now := time.Date(2022, 3, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		volume gobizfly.Volume
+		days   int
+		since  string
+		ok     bool
+	}{
+		{name: "updated", volume: gobizfly.Volume{UpdatedAt: "2022-03-01T12:00:00.000000", CreatedAt: "2021-01-01T00:00:00"},
+			days: 30, since: "updated_at", ok: true},
+		{name: "rfc3339", volume: gobizfly.Volume{UpdatedAt: "2022-03-30T12:00:00Z"}, days: 1, since: "updated_at", ok: true},
+		{name: "created", volume: gobizfly.Volume{CreatedAt: "2022-01-30 12:00:00"}, days: 60, since: "created_at", ok: true},
+		{name: "invalid update", volume: gobizfly.Volume{UpdatedAt: "yesterday", CreatedAt: "2022-03-21T12:00:00"},
+			days: 10, since: "created_at", ok: true},
+		{name: "no time", volume: gobizfly.Volume{}},
+	}
+	for _, tt := range tests {
+		days, since, ok := volumeIdleDays(&tt.volume, now)
+		if days != tt.days || since != tt.since || ok != tt.ok {
+			t.Errorf("%s: volumeIdleDays = %d, %s, %v, want %d, %s, %v", tt.name, days, since, ok, tt.days, tt.since, tt.ok)
+		}
+	}
+}
+
+func TestVolumeMonthlyCost(t *testing.T) {
+	p := &pricing{HoursPerMonth: 730, Volume: map[string]price{"SSD": {OnDemand: 5, SavingPlan: 4}}}
+	tests := []struct {
+		volume gobizfly.Volume
+		want   float64
+		ok     bool
+	}{
+		{volume: gobizfly.Volume{VolumeType: "PREMIUM-SSD1", Size: 10, BillingPlan: "on_demand"}, want: 10 * 5 * 730, ok: true},
+		{volume: gobizfly.Volume{VolumeType: "PREMIUM-SSD1", Size: 10, BillingPlan: "saving_plan"}, want: 10 * 4 * 730, ok: true},
+		{volume: gobizfly.Volume{VolumeType: "CUSTOM", Type: "SSD", Size: 1, BillingPlan: "on_demand"}, want: 5 * 730, ok: true},
+		{volume: gobizfly.Volume{VolumeType: "PREMIUM-HDD1", Type: "HDD", Size: 10}},
+	}
+	for _, tt := range tests {
+		got, ok := p.volumeMonthlyCost(&tt.volume)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("volumeMonthlyCost(%s %s) = %v, %v, want %v, %v", tt.volume.VolumeType, tt.volume.BillingPlan, got,
+				ok, tt.want, tt.ok)
+		}
+	}
+}
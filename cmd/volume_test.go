@@ -0,0 +1,59 @@
+/*
+Copyright © (2020-2022) Bizfly Cloud
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bizflycloud/gobizfly"
+)
+
+func TestFilterVolumes(t *testing.T) {
+	volumes := []*gobizfly.Volume{
+		{ID: "1", Status: "in-use", VolumeType: "PREMIUM-SSD1", Type: "SSD", AvailabilityZone: "HN1", Bootable: true,
+			Attachments: []gobizfly.VolumeAttachment{{ServerID: "server-1"}}},
+		{ID: "2", Status: "available", VolumeType: "PREMIUM-HDD1", Type: "HDD", AvailabilityZone: "HN1"},
+		{ID: "3", Status: "in-use", VolumeType: "PREMIUM-HDD1", Type: "HDD", AvailabilityZone: "HN2",
+			Attachments: []gobizfly.VolumeAttachment{{ServerID: "server-2"}}},
+	}
+	yes, no := true, false
+	tests := []struct {
+		name   string
+		filter volumeFilter
+		want   []string
+	}{
+		{name: "no filter", want: []string{"1", "2", "3"}},
+		{name: "status", filter: volumeFilter{Status: "AVAILABLE"}, want: []string{"2"}},
+		{name: "volume type", filter: volumeFilter{Type: "premium-hdd1"}, want: []string{"2", "3"}},
+		{name: "disk type", filter: volumeFilter{Type: "ssd"}, want: []string{"1"}},
+		{name: "zone", filter: volumeFilter{Zone: "HN2"}, want: []string{"3"}},
+		{name: "attached", filter: volumeFilter{Attached: &yes}, want: []string{"1", "3"}},
+		{name: "detached", filter: volumeFilter{Attached: &no}, want: []string{"2"}},
+		{name: "bootable", filter: volumeFilter{Bootable: &yes}, want: []string{"1"}},
+		{name: "server", filter: volumeFilter{ServerID: "server-2"}, want: []string{"3"}},
+		{name: "detached in use", filter: volumeFilter{Status: "in-use", Attached: &no}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, volume := range filterVolumes(volumes, &tt.filter) {
+			got = append(got, volume.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: filterVolumes = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -74,6 +74,11 @@ var wanIPCreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create WAN IP",
 	Run: func(cmd *cobra.Command, args []string) {
+		if estimateCost {
+			p := loadPricing()
+			p.printEstimate([]costLine{p.wanIPLine(1)})
+			return
+		}
 		client, ctx := getApiClient(cmd)
 		payload := gobizfly.CreatePublicNetworkInterfacePayload{
 			Name:             wanIpName,
@@ -276,6 +281,7 @@ func init() {
 	wicpf.StringVar(&availabilityZone, "zone", "", "The availability zone")
 	wicpf.StringVar(&wanIpName, "name", "", "The WAN IP name")
 	wicpf.StringVar(&serverID, "server-id", "", "The server id want to attach")
+	addEstimateFlags(wanIPCreateCmd)
 	wanIPCmd.AddCommand(wanIPCreateCmd)
 
 	wiaspf := wanIpAttachServerCmd.PersistentFlags()
@@ -1,12 +1,20 @@
 package formatter
 
 import (
+	"encoding/json"
 	"os"
 
+	"github.com/jedib0t/go-pretty/list"
 	"github.com/jedib0t/go-pretty/table"
 	"github.com/olekukonko/tablewriter"
 )
 
+// TreeNode is a node of the tree printed by TreeOutput
+type TreeNode struct {
+	Text     string
+	Children []*TreeNode
+}
+
 // Output is func support string data
 func Output(header []string, data [][]string) {
 	table := tablewriter.NewWriter(os.Stdout)
@@ -34,3 +42,33 @@ func SimpleOutput(header table.Row, rows []table.Row) {
 	t.AppendRows(rows)
 	t.Render()
 }
+
+// TreeOutput is func support tree data
+func TreeOutput(nodes ...*TreeNode) {
+	l := list.NewWriter()
+	l.SetOutputMirror(os.Stdout)
+	l.SetStyle(list.StyleConnectedLight)
+	for _, node := range nodes {
+		appendTreeNode(l, node)
+	}
+	l.Render()
+}
+
+func appendTreeNode(l list.Writer, node *TreeNode) {
+	l.AppendItem(node.Text)
+	if len(node.Children) == 0 {
+		return
+	}
+	l.Indent()
+	for _, child := range node.Children {
+		appendTreeNode(l, child)
+	}
+	l.UnIndent()
+}
+
+// JSONOutput is func support structured data
+func JSONOutput(data interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}